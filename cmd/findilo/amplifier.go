@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const amplifierNodesPath = "/redfish/v1/AggregatorService/ManagedNodes"
+
+// AmplifierNode is a server registered in iLO Amplifier Pack.
+type AmplifierNode struct {
+	ManagerIPAddress       string `json:"ManagerIPAddress"`
+	ManagerType            string `json:"ManagerType"`
+	ManagerFirmwareVersion string `json:"ManagerFirmwareVersion"`
+	ManagerHostName        string `json:"ManagerHostName"`
+	SerialNumber           string `json:"SerialNumber"`
+	Model                  string `json:"Model"`
+	ServerName             string `json:"ServerName"`
+}
+
+// AmplifierNodes is an expanded page of ManagedNodes.
+type AmplifierNodes struct {
+	Members  []AmplifierNode `json:"Members"`
+	NextLink string          `json:"Members@odata.nextLink"`
+}
+
+func (n AmplifierNode) info() ILOInfo {
+	return ILOInfo{
+		IP:         n.ManagerIPAddress,
+		HW:         orNotAvailable(n.ManagerType),
+		FW:         orNotAvailable(n.ManagerFirmwareVersion),
+		Serial:     orNotAvailable(strings.TrimSpace(n.SerialNumber)),
+		Model:      orNotAvailable(n.Model),
+		ServerName: n.ServerName,
+		IloName:    n.ManagerHostName,
+		DeviceType: deviceILO,
+	}
+}
+
+// amplifierFilter turns model=... and fw=... filters into an OData
+// $filter expression.
+func amplifierFilter(filters []string) (string, error) {
+	fields := map[string]string{
+		"model": "Model",
+		"fw":    "ManagerFirmwareVersion",
+	}
+	exprs := []string{}
+	for _, f := range filters {
+		kv := strings.SplitN(f, "=", 2)
+		field, ok := fields[kv[0]]
+		if len(kv) != 2 || !ok {
+			return "", fmt.Errorf("invalid amplifier filter %q, want model=VALUE or fw=VALUE", f)
+		}
+		exprs = append(exprs, fmt.Sprintf("%s eq '%s'", field, strings.Replace(kv[1], "'", "''", -1)))
+	}
+	return strings.Join(exprs, " and "), nil
+}
+
+// requestAmplifier reads all servers registered in an iLO Amplifier Pack
+// instead of scanning networks.
+func requestAmplifier(cfg *Config, base, token string, filters []string) ([]ILOInfo, error) {
+	filter, err := amplifierFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("$expand", ".")
+	if filter != "" {
+		query.Set("$filter", filter)
+	}
+	base = strings.TrimSuffix(base, "/")
+	next := amplifierNodesPath + "?" + query.Encode()
+	ilo := []ILOInfo{}
+	for next != "" {
+		req, err := http.NewRequest("GET", base+next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Auth-Token", token)
+		resp, err := iloClient(cfg).Do(req)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		logResponse(resp, raw)
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: %s", next, resp.Status)
+		}
+		page := &AmplifierNodes{}
+		if err := json.Unmarshal(raw, page); err != nil {
+			return nil, err
+		}
+		for _, node := range page.Members {
+			ilo = append(ilo, node.info())
+		}
+		next = page.NextLink
+	}
+	return ilo, nil
+}
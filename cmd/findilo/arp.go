@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+// arpWait is how long ARP replies are awaited after the last request.
+const arpWait = time.Second
+
+const (
+	ethTypeARP   = 0x0806
+	arpRequestOp = 1
+	arpReplyOp   = 2
+	arpFrameLen  = 42
+)
+
+// arpMACs are the MAC addresses of the hosts that answered --arp, by IP.
+// It is filled before the scan starts and only read afterwards.
+var arpMACs = map[string]string{}
+
+// arpSweep ARP scans the targets on the directly attached IPv4 networks
+// and drops the ones there that did not answer. Targets on other networks
+// are kept. Raw packet sockets need root or CAP_NET_RAW; without them the
+// error is returned.
+func arpSweep(ips scanner.Targets) (scanner.Targets, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ips, err
+	}
+	local := []*net.IPNet{}
+	alive := []string{}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return ips, err
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			network := &net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}
+			targets := []net.IP{}
+			ips.Each(func(ip string) bool {
+				if addr := net.ParseIP(ip); network.Contains(addr) {
+					targets = append(targets, addr.To4())
+				}
+				return true
+			})
+			if len(targets) == 0 {
+				continue
+			}
+			macs, err := arpScanInterface(iface, ipnet.IP.To4(), targets)
+			if err != nil {
+				return ips, err
+			}
+			logger.Infof("arp: %d of %d hosts on %s answered", len(macs), len(targets), iface.Name)
+			local = append(local, network)
+			for ip, mac := range macs {
+				arpMACs[ip] = mac
+				alive = append(alive, ip)
+			}
+		}
+	}
+	if len(local) == 0 {
+		return ips, nil
+	}
+	return ips.Exclude(local).Union(scanner.TargetsOf(alive)), nil
+}
+
+// arpRequest builds a broadcast ARP request for target.
+func arpRequest(srcMAC net.HardwareAddr, srcIP, target net.IP) []byte {
+	frame := make([]byte, arpFrameLen)
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:], ethTypeARP)
+	binary.BigEndian.PutUint16(frame[14:], 1)      // Ethernet
+	binary.BigEndian.PutUint16(frame[16:], 0x0800) // IPv4
+	frame[18] = 6
+	frame[19] = 4
+	binary.BigEndian.PutUint16(frame[20:], arpRequestOp)
+	copy(frame[22:28], srcMAC)
+	copy(frame[28:32], srcIP.To4())
+	copy(frame[38:42], target.To4())
+	return frame
+}
+
+// parseARPReply returns the sender of an ARP reply frame.
+func parseARPReply(frame []byte) (net.IP, net.HardwareAddr, bool) {
+	if len(frame) < arpFrameLen || binary.BigEndian.Uint16(frame[12:]) != ethTypeARP ||
+		binary.BigEndian.Uint16(frame[20:]) != arpReplyOp || !bytes.Equal(frame[18:20], []byte{6, 4}) {
+		return nil, nil, false
+	}
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, frame[22:28])
+	return net.IP(append([]byte(nil), frame[28:32]...)), mac, true
+}
@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// arpScanInterface sends an ARP request for every target out of iface and
+// returns the MAC addresses of the targets that answered, by IP.
+func arpScanInterface(iface *net.Interface, src net.IP, targets []net.IP) (map[string]string, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(ethTypeARP)))
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(ethTypeARP), Ifindex: iface.Index}); err != nil {
+		return nil, os.NewSyscallError("bind", err)
+	}
+	tv := syscall.NsecToTimeval(int64(100 * time.Millisecond))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+
+	wanted := make(map[string]bool, len(targets))
+	for _, ip := range targets {
+		wanted[ip.String()] = true
+	}
+	var mu sync.Mutex
+	macs := map[string]string{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1500)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				logger.Debugf("arp: %s: %v", iface.Name, err)
+				return
+			}
+			ip, mac, ok := parseARPReply(buf[:n])
+			if !ok || !wanted[ip.String()] {
+				continue
+			}
+			mu.Lock()
+			macs[ip.String()] = mac.String()
+			mu.Unlock()
+		}
+	}()
+
+	dst := &syscall.SockaddrLinklayer{Protocol: htons(ethTypeARP), Ifindex: iface.Index, Halen: 6}
+	copy(dst.Addr[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	for _, ip := range targets {
+		dialLimiter.wait()
+		if err := syscall.Sendto(fd, arpRequest(iface.HardwareAddr, src, ip), 0, dst); err != nil {
+			logger.Debugf("%s: arp: %v", ip, err)
+		}
+	}
+	time.Sleep(arpWait)
+	close(stop)
+	<-done
+	return macs, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+// arpScanInterface fails where raw packet sockets are not supported.
+func arpScanInterface(iface *net.Interface, src net.IP, targets []net.IP) (map[string]string, error) {
+	return nil, errors.New("ARP scanning is only supported on Linux")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net"
+)
+
+// localNetworks returns the private (RFC 1918) IPv4 networks of the local
+// interfaces. Networks with a prefix shorter than minPrefix are skipped,
+// so that a laptop on a 10.0.0.0/8 does not start a 16 million host scan.
+func localNetworks(minPrefix int) ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	nets := []string{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			network := &net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}
+			if !ipnet.IP.IsPrivate() {
+				logger.Infof("%s on %s: skipped, not a private network", network, iface.Name)
+				continue
+			}
+			if ones, _ := ipnet.Mask.Size(); ones < minPrefix {
+				logger.Warnf("%s on %s: skipped, larger than --auto-min-prefix /%d", network, iface.Name, minPrefix)
+				continue
+			}
+			nets = append(nets, network.String())
+		}
+	}
+	return nets, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const ec2APIVersion = "2016-11-15"
+
+// ec2Endpoint is the DescribeInstances URL, formatted with the region.
+var ec2Endpoint = "https://ec2.%s.amazonaws.com/"
+
+// EC2Instances is the part of the DescribeInstances response needed to map
+// private IPs to instances.
+type EC2Instances struct {
+	Reservations []struct {
+		Instances []struct {
+			InstanceID       string `xml:"instanceId"`
+			PrivateIPAddress string `xml:"privateIpAddress"`
+			Interfaces       []struct {
+				Addresses []string `xml:"privateIpAddressesSet>item>privateIpAddress"`
+			} `xml:"networkInterfaceSet>item"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func awsCredentialsFromEnv() (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return creds, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return creds, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// signAWSv4 adds a Signature Version 4 Authorization header to a request
+// with the given body. It signs the Content-Type header when it is set.
+func signAWSv4(req *http.Request, body, region, service string, creds awsCredentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	signed := []string{"host", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signed = append([]string{"content-type"}, signed...)
+	}
+	if creds.sessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	canonicalHeaders := ""
+	for _, h := range signed {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders += h + ":" + strings.TrimSpace(value) + "\n"
+	}
+	signedHeaders := strings.Join(signed, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonical)
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+// requestEC2Addresses returns the instance ID of every private IP of the
+// EC2 instances in region.
+func requestEC2Addresses(ctx context.Context, region string) (map[string]string, error) {
+	creds, err := awsCredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf(ec2Endpoint, region)
+	addresses := map[string]string{}
+	token := ""
+	for {
+		form := url.Values{}
+		form.Set("Action", "DescribeInstances")
+		form.Set("Version", ec2APIVersion)
+		if token != "" {
+			form.Set("NextToken", token)
+		}
+		body := form.Encode()
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		req.Header.Set("User-Agent", userAgent)
+		signAWSv4(req, body, region, "ec2", creds, time.Now())
+
+		resp, err := newHTTPClient(config).Do(req)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		logResponse(resp, raw)
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("DescribeInstances: %s", resp.Status)
+		}
+		page := &EC2Instances{}
+		if err := xml.Unmarshal(raw, page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Reservations {
+			for _, inst := range r.Instances {
+				if inst.PrivateIPAddress != "" {
+					addresses[inst.PrivateIPAddress] = inst.InstanceID
+				}
+				for _, iface := range inst.Interfaces {
+					for _, ip := range iface.Addresses {
+						addresses[ip] = inst.InstanceID
+					}
+				}
+			}
+		}
+		if page.NextToken == "" {
+			return addresses, nil
+		}
+		token = page.NextToken
+	}
+}
+
+// correlateEC2 sets the EC2 instance of every host whose IP belongs to an
+// instance in region.
+func correlateEC2(ctx context.Context, ilo []ILOInfo, region string) error {
+	addresses, err := requestEC2Addresses(ctx, region)
+	if err != nil {
+		return err
+	}
+	for i := range ilo {
+		if id, ok := addresses[ilo[i].IP]; ok {
+			ilo[i].EC2InstanceID = id
+			ilo[i].EC2Region = region
+		}
+	}
+	return nil
+}
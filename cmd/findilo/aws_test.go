@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// awsExample is the key pair of the AWS Signature Version 4 test suite.
+var awsExample = awsCredentials{
+	accessKey: "AKIDEXAMPLE",
+	secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+// TestSignAWSv4 checks the signatures of the get-vanilla and
+// post-x-www-form-urlencoded cases of the AWS test suite.
+func TestSignAWSv4(t *testing.T) {
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:   "get-vanilla",
+			method: "GET",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:        "post-x-www-form-urlencoded",
+			method:      "POST",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "Param1=value1",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, "https://example.amazonaws.com/", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		signAWSv4(req, tt.body, "us-east-1", "service", awsExample, now)
+		if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("%s: X-Amz-Date = %q", tt.name, got)
+		}
+		if got := req.Header.Get("Authorization"); got != tt.want {
+			t.Errorf("%s: Authorization = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+const describeInstancesPage = `<?xml version="1.0" encoding="UTF-8"?>
+<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>8f7724cf-496f-496e-8fe3-example</requestId>
+  <reservationSet>
+    <item>
+      <reservationId>r-1</reservationId>
+      <instancesSet>
+        <item>
+          <instanceId>%s</instanceId>
+          <privateIpAddress>%s</privateIpAddress>
+          <networkInterfaceSet>
+            <item>
+              <privateIpAddressesSet>
+                <item><privateIpAddress>%[2]s</privateIpAddress><primary>true</primary></item>
+                <item><privateIpAddress>%s</privateIpAddress><primary>false</primary></item>
+              </privateIpAddressesSet>
+            </item>
+          </networkInterfaceSet>
+        </item>
+      </instancesSet>
+    </item>
+  </reservationSet>
+  %s
+</DescribeInstancesResponse>`
+
+func TestCorrelateEC2(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", awsExample.accessKey)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", awsExample.secretKey)
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	pages := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if r.Form.Get("Action") != "DescribeInstances" || r.Form.Get("Version") != ec2APIVersion {
+			t.Errorf("form = %v", r.Form)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/eu-west-1/ec2/aws4_request") {
+			t.Errorf("Authorization = %q, want the eu-west-1 ec2 scope", auth)
+		}
+		switch r.Form.Get("NextToken") {
+		case "":
+			fmt.Fprintf(w, describeInstancesPage, "i-1", "10.0.0.5", "10.0.0.6", "<nextToken>page2</nextToken>")
+		case "page2":
+			fmt.Fprintf(w, describeInstancesPage, "i-2", "10.0.1.5", "10.0.1.6", "")
+		default:
+			http.Error(w, "bad token", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	defer func(endpoint string) { ec2Endpoint = endpoint }(ec2Endpoint)
+	ec2Endpoint = srv.URL + "/%s/"
+
+	ilo := []ILOInfo{{IP: "10.0.0.5"}, {IP: "10.0.0.6"}, {IP: "10.0.1.6"}, {IP: "10.0.2.1"}}
+	if err := correlateEC2(context.Background(), ilo, "eu-west-1"); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 2 {
+		t.Errorf("requested %d pages, want 2", pages)
+	}
+	want := []string{"i-1", "i-1", "i-2", ""}
+	for i, info := range ilo {
+		if info.EC2InstanceID != want[i] {
+			t.Errorf("%s: instance %q, want %q", info.IP, info.EC2InstanceID, want[i])
+		}
+		if region := info.EC2Region; (want[i] != "") != (region == "eu-west-1") {
+			t.Errorf("%s: region %q", info.IP, region)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hdhog/findilo/pkg/fingerprint"
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+// iloBannerHeaders are response headers only sent by iLO web servers.
+var iloBannerHeaders = []string{
+	"X-Auth-Token",
+	"X-HPRESTFULAPI-AuthenticationChallenge",
+}
+
+var iloBannerStrings = []string{
+	"Integrated Lights-Out",
+	"iLO",
+}
+
+// bannerFingerprinter finds iLOs that only answer on the HTTPS port by
+// their start page, with --check-https-banner.
+type bannerFingerprinter struct {
+	cfg *Config
+}
+
+func init() {
+	fingerprint.Register(orderBanner, "https banner", bannerFingerprinter{cfg: &config})
+}
+
+func (f bannerFingerprinter) Ports() []int {
+	if !f.cfg.HTTPSBanner {
+		return nil
+	}
+	return []int{httpsPort}
+}
+
+func (f bannerFingerprinter) Identify(ctx context.Context, host string, port int) (*fingerprint.Device, bool, error) {
+	ok, err := requestHTTPSBanner(ctx, f.cfg, host)
+	if err != nil {
+		logger.Debugf("%s: %v", host, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	info := &ILOInfo{
+		IP:         host,
+		HW:         notAvailable,
+		FW:         notAvailable,
+		Model:      notAvailable,
+		Serial:     notAvailable,
+		DeviceType: deviceILO,
+		ILOOnHTTPS: true,
+	}
+	info.ServerName, info.IloName, err = hpilo.FetchSessionNames(ctx, newILODoer(f.cfg), host, 0)
+	if err != nil {
+		return info.device(), true, ILOError{IP: host, Phase: phaseServerName, Err: err}
+	}
+	return info.device(), true, nil
+}
+
+// Enrich has nothing to add: the details need the iLO XML.
+func (bannerFingerprinter) Enrich(ctx context.Context, dev *fingerprint.Device) error {
+	return nil
+}
+
+// requestHTTPSBanner identifies an iLO by the headers and body of its
+// HTTPS start page.
+func requestHTTPSBanner(ctx context.Context, cfg *Config, ip string) (bool, error) {
+	url := hpilo.URL("https", ip, 0, "/")
+	resp, err := getContext(ctx, iloClient(cfg), url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	logResponse(resp, raw)
+	for _, h := range iloBannerHeaders {
+		if _, ok := resp.Header[http.CanonicalHeaderKey(h)]; ok {
+			return true, nil
+		}
+	}
+	for _, s := range iloBannerStrings {
+		if strings.Contains(string(raw), s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
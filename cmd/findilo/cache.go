@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+// scanCache holds what Identify read from each host: the XML fields, the
+// names and the checksum of the XML. Entries written before the
+// snake_case host keys have no xml_sha1 and never match, so they are read
+// again.
+type scanCache struct {
+	sync.Mutex
+	hosts map[string]hpilo.Info
+}
+
+var cache = &scanCache{hosts: map[string]hpilo.Info{}}
+
+func (c *scanCache) load(path string) error {
+	raw, err := ioutil.ReadFile(expandHome(path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	return json.Unmarshal(raw, &c.hosts)
+}
+
+func (c *scanCache) save(path string) error {
+	c.Lock()
+	raw, err := json.MarshalIndent(c.hosts, "", "  ")
+	c.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(expandHome(path), raw)
+}
+
+// lookup returns the cached host, for hpilo.Discover to compare its
+// checksum with the XML.
+func (c *scanCache) lookup(ip string) *hpilo.Info {
+	c.Lock()
+	defer c.Unlock()
+	info, ok := c.hosts[ip]
+	if !ok {
+		return nil
+	}
+	return &info
+}
+
+func (c *scanCache) store(ip string, info hpilo.Info) {
+	c.Lock()
+	defer c.Unlock()
+	c.hosts[ip] = info
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+const testRIMP = `<RIMP><HSI><SBSN>CZ1234ABCD </SBSN><SPN>ProLiant DL380 Gen9</SPN></HSI>` +
+	`<MP><PN>Integrated Lights-Out 4 (iLO 4)</PN><FWRI>2.55</FWRI><HWRI>ASIC: 16</HWRI></MP></RIMP>`
+
+func TestIdentifyCacheHitSkipsOnlyIdentify(t *testing.T) {
+	var names int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xmldata":
+			w.Write([]byte(testRIMP))
+		case "/json/login_session":
+			atomic.AddInt32(&names, 1)
+			w.Write([]byte(`{"server_name":"srv01","cn":"ilo-srv01"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	saved := cache
+	cache = &scanCache{hosts: map[string]hpilo.Info{}}
+	defer func() { cache = saved }()
+
+	cfg := validConfig()
+	cfg.SkipUnchanged = true
+	f := iloFingerprinter{cfg: &cfg}
+	dev, _, err := f.Identify(context.Background(), "127.0.0.1", port)
+	if dev == nil || err != nil || dev.ServerName != "srv01" {
+		t.Fatalf("first Identify = %+v, %v", dev, err)
+	}
+	// What Enrich adds to the host must not be stored with it.
+	first := deviceInfo(dev)
+	first.CertExpiry = time.Now()
+	first.Warnings = append(first.Warnings, "certificate expired")
+
+	dev, _, err = f.Identify(context.Background(), "127.0.0.1", port)
+	if dev == nil || err != nil {
+		t.Fatalf("second Identify = %+v, %v", dev, err)
+	}
+	second := deviceInfo(dev)
+	if n := atomic.LoadInt32(&names); n != 1 {
+		t.Errorf("names were requested %d times, want 1", n)
+	}
+	if second.ServerName != "srv01" || second.IloName != "ilo-srv01" || second.Serial != "CZ1234ABCD" {
+		t.Errorf("cached host = %+v", second)
+	}
+	if !second.CertExpiry.IsZero() || len(second.Warnings) != 0 {
+		t.Errorf("cached host has the enrichment of the first scan: %+v", second)
+	}
+}
+
+func TestIdentifyNotCachedWithoutNames(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xmldata" {
+			w.Write([]byte(testRIMP))
+			return
+		}
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	saved := cache
+	cache = &scanCache{hosts: map[string]hpilo.Info{}}
+	defer func() { cache = saved }()
+
+	cfg := validConfig()
+	cfg.SkipUnchanged = true
+	dev, _, err := iloFingerprinter{cfg: &cfg}.Identify(context.Background(), "127.0.0.1", port)
+	if dev == nil {
+		t.Fatal("host with a readable XML was not taken for an iLO")
+	}
+	if e, ok := err.(ILOError); !ok || e.Phase != phaseServerName {
+		t.Errorf("Identify failed with %v, want a %s error", err, phaseServerName)
+	}
+	if n := len(cache.hosts); n != 0 {
+		t.Errorf("cache has %d hosts after a failed names request, want 0", n)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// certWarnPeriod is how long before expiry a certificate gets a warning.
+const certWarnPeriod = 30 * 24 * time.Hour
+
+// requestCertExpiry returns the expiry date of the HTTPS certificate.
+func requestCertExpiry(ctx context.Context, cfg *Config, ip string) (time.Time, error) {
+	if err := dialLimiter.waitContext(ctx); err != nil {
+		return time.Time{}, err
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: cfg.HTTPTimeout},
+		Config:    &tls.Config{InsecureSkipVerify: true},
+	}
+	c, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(httpsPort)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	conn := c.(*tls.Conn)
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no peer certificate")
+	}
+	return certs[0].NotAfter, nil
+}
+
+func checkCert(ctx context.Context, cfg *Config, ip string, info *ILOInfo, now time.Time) error {
+	expiry, err := requestCertExpiry(ctx, cfg, ip)
+	if err != nil {
+		return err
+	}
+	info.CertExpiry = expiry
+	if left := expiry.Sub(now); left < certWarnPeriod {
+		if left < 0 {
+			info.Warnings = append(info.Warnings, "certificate expired")
+		} else {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("certificate expires in %d days", int(left.Hours()/24)))
+		}
+	}
+	return nil
+}
+
+func certExpiryString(info ILOInfo) string {
+	if info.CertExpiry.IsZero() {
+		return notAvailable
+	}
+	s := info.CertExpiry.Format("2006-01-02")
+	if info.CertExpiry.Sub(time.Now()) < certWarnPeriod {
+		s += " !"
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+var (
+	diffOld = diffCmd.Arg("old", "Earlier scan.").Required().ExistingFile()
+	diffNew = diffCmd.Arg("new", "Later scan.").Required().ExistingFile()
+)
+
+// Kinds of HostChange.
+const (
+	changeAdded    = "added"
+	changeRemoved  = "removed"
+	changeChanged  = "changed"
+	changeMoved    = "moved"
+	changeReplaced = "replaced"
+)
+
+// HostChange is one difference between two scans.
+type HostChange struct {
+	Kind   string `json:"kind"`
+	Serial string `json:"serial"`
+	IP     string `json:"ip"`
+	Field  string `json:"field,omitempty"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// hostKey is the serial number, which survives readdressing, or the IP
+// for hosts without one.
+func hostKey(info ILOInfo) string {
+	serial := strings.TrimSpace(info.Serial)
+	if serial == "" || serial == notAvailable {
+		return "ip:" + info.IP
+	}
+	return serial
+}
+
+func fieldChanges(old, cur ILOInfo) []HostChange {
+	fields := []struct {
+		name     string
+		old, cur string
+	}{
+		{"hw", old.HW, cur.HW},
+		{"fw", old.FW, cur.FW},
+		{"model", old.Model, cur.Model},
+		{"server name", old.ServerName, cur.ServerName},
+		{"ilo name", old.IloName, cur.IloName},
+	}
+	changes := []HostChange{}
+	for _, f := range fields {
+		if f.old != f.cur {
+			changes = append(changes, HostChange{Kind: changeChanged, Serial: cur.Serial, IP: cur.IP, Field: f.name, Old: f.old, New: f.cur})
+		}
+	}
+	return changes
+}
+
+// diffHosts compares two scans keyed on the serial number. A known serial
+// at a new IP is a moved host. A new serial at the IP of a vanished one is
+// a replaced host. Hosts sharing a serial are paired by IP first, so a
+// duplicate does not hide the other.
+func diffHosts(prev, cur []ILOInfo) []HostChange {
+	before := map[string][]int{}
+	for i, info := range prev {
+		k := hostKey(info)
+		before[k] = append(before[k], i)
+	}
+	// match[j] is the index in prev of cur[j], or -1.
+	match := make([]int, len(cur))
+	matched := make([]bool, len(prev))
+	for j := range match {
+		match[j] = -1
+	}
+	pair := func(sameIP bool) {
+		for j, info := range cur {
+			if match[j] >= 0 {
+				continue
+			}
+			for _, i := range before[hostKey(info)] {
+				if !matched[i] && (!sameIP || prev[i].IP == info.IP) {
+					match[j], matched[i] = i, true
+					break
+				}
+			}
+		}
+	}
+	pair(true)
+	pair(false)
+	removedByIP := map[string]ILOInfo{}
+	for i, info := range prev {
+		if !matched[i] {
+			removedByIP[info.IP] = info
+		}
+	}
+
+	changes := []HostChange{}
+	for j, info := range cur {
+		if match[j] < 0 {
+			if gone, ok := removedByIP[info.IP]; ok {
+				changes = append(changes, HostChange{Kind: changeReplaced, Serial: info.Serial, IP: info.IP, Field: "serial", Old: gone.Serial, New: info.Serial})
+				delete(removedByIP, info.IP)
+				continue
+			}
+			changes = append(changes, HostChange{Kind: changeAdded, Serial: info.Serial, IP: info.IP, New: info.HW + " " + info.FW})
+			continue
+		}
+		old := prev[match[j]]
+		if old.IP != info.IP {
+			changes = append(changes, HostChange{Kind: changeMoved, Serial: info.Serial, IP: info.IP, Field: "ip", Old: old.IP, New: info.IP})
+		}
+		changes = append(changes, fieldChanges(old, info)...)
+	}
+	for i, info := range prev {
+		if _, ok := removedByIP[info.IP]; ok && !matched[i] {
+			changes = append(changes, HostChange{Kind: changeRemoved, Serial: info.Serial, IP: info.IP, Old: info.HW + " " + info.FW})
+		}
+	}
+	return changes
+}
+
+func hostChangesRender(changes []HostChange) {
+	data := [][]string{}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Change", "S/N", "IP", "Field", "Old", "New"})
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, c := range changes {
+		data = append(data, []string{c.Kind, c.Serial, c.IP, c.Field, c.Old, c.New})
+	}
+	table.AppendBulk(data)
+	table.Render()
+}
+
+// diffCommand prints added, removed and changed hosts between two scans.
+func diffCommand() {
+	prev, err := readJSON(*diffOld)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	cur, err := readJSON(*diffNew)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	changes := diffHosts(prev.Hosts, cur.Hosts)
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	hostChangesRender(changes)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffHosts(t *testing.T) {
+	host := func(ip, serial, fw string) ILOInfo {
+		return ILOInfo{IP: ip, Serial: serial, HW: "ILO4", FW: fw}
+	}
+	tests := []struct {
+		name      string
+		prev, cur []ILOInfo
+		want      []HostChange
+	}{
+		{
+			name: "unchanged",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			want: []HostChange{},
+		},
+		{
+			name: "added",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.1", "CZ1", "2.50"), host("10.0.0.2", "CZ2", "2.55")},
+			want: []HostChange{{Kind: changeAdded, Serial: "CZ2", IP: "10.0.0.2", New: "ILO4 2.55"}},
+		},
+		{
+			name: "removed",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50"), host("10.0.0.2", "CZ2", "2.55")},
+			cur:  []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			want: []HostChange{{Kind: changeRemoved, Serial: "CZ2", IP: "10.0.0.2", Old: "ILO4 2.55"}},
+		},
+		{
+			name: "changed",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.1", "CZ1", "2.55")},
+			want: []HostChange{{Kind: changeChanged, Serial: "CZ1", IP: "10.0.0.1", Field: "fw", Old: "2.50", New: "2.55"}},
+		},
+		{
+			name: "moved",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.9", "CZ1", "2.50")},
+			want: []HostChange{{Kind: changeMoved, Serial: "CZ1", IP: "10.0.0.9", Field: "ip", Old: "10.0.0.1", New: "10.0.0.9"}},
+		},
+		{
+			name: "replaced",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.1", "CZ9", "2.50")},
+			want: []HostChange{{Kind: changeReplaced, Serial: "CZ9", IP: "10.0.0.1", Field: "serial", Old: "CZ1", New: "CZ9"}},
+		},
+		{
+			name: "no serial keyed on IP",
+			prev: []ILOInfo{host("10.0.0.1", "", "2.50"), host("10.0.0.2", notAvailable, "1.40")},
+			cur:  []ILOInfo{host("10.0.0.1", "", "2.55"), host("10.0.0.3", notAvailable, "1.40")},
+			want: []HostChange{
+				{Kind: changeChanged, IP: "10.0.0.1", Field: "fw", Old: "2.50", New: "2.55"},
+				{Kind: changeAdded, Serial: notAvailable, IP: "10.0.0.3", New: "ILO4 1.40"},
+				{Kind: changeRemoved, Serial: notAvailable, IP: "10.0.0.2", Old: "ILO4 1.40"},
+			},
+		},
+		{
+			name: "duplicate serial",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50"), host("10.0.0.2", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.2", "CZ1", "2.55"), host("10.0.0.1", "CZ1", "2.50")},
+			want: []HostChange{{Kind: changeChanged, Serial: "CZ1", IP: "10.0.0.2", Field: "fw", Old: "2.50", New: "2.55"}},
+		},
+		{
+			name: "duplicate serial removed",
+			prev: []ILOInfo{host("10.0.0.1", "CZ1", "2.50"), host("10.0.0.2", "CZ1", "2.50")},
+			cur:  []ILOInfo{host("10.0.0.2", "CZ1", "2.50")},
+			want: []HostChange{{Kind: changeRemoved, Serial: "CZ1", IP: "10.0.0.1", Old: "ILO4 2.50"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := diffHosts(tt.prev, tt.cur); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diffHosts = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
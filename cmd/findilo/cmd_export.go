@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	exportScan   = exportCmd.Arg("scan", "Scan written with --output json.").Required().ExistingFile()
+	exportFormat = exportCmd.Flag("format", "Format to convert the scan to.").Short('f').Default("csv").Enum("csv", "html", "markdown", "xlsx", "yaml", "hosts")
+	exportOut    = exportCmd.Flag("out", "File to write instead of stdout, required for xlsx.").PlaceHolder("FILE").String()
+)
+
+// exportCommand writes a saved scan in another format, without scanning
+// again.
+func exportCommand() {
+	scan, err := readJSON(*exportScan)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if *exportFormat == "xlsx" {
+		if *exportOut == "" {
+			kingpin.Fatalf("--format xlsx requires --out")
+		}
+		if err := writeXLSX(*exportOut, xlsxTables(scan.Hosts), *outdatedFW); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	w := io.Writer(os.Stdout)
+	if *exportOut != "" {
+		f, err := os.Create(*exportOut)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := exportScanTo(w, scan, *exportFormat); err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+func exportScanTo(w io.Writer, scan *ScanReport, format string) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, scan.Hosts)
+	case "html":
+		return writeHTML(w, scan.Hosts, failedHosts(scan.Failures), nil, 0, scan.ScannedAt.Local())
+	case "markdown":
+		return writeMarkdown(w, scan.Hosts)
+	case "yaml":
+		return writeYAML(w, *scan)
+	case "hosts":
+		hostsRender(w, scan.Hosts)
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// failedHosts turns the failures of a saved scan back into ILOErrors.
+func failedHosts(records []failureRecord) []ILOError {
+	res := make([]ILOError, 0, len(records))
+	for _, r := range records {
+		res = append(res, ILOError{IP: r.IP, Phase: r.Phase, Err: errors.New(r.Error)})
+	}
+	return res
+}
@@ -0,0 +1,18 @@
+package main
+
+import "os"
+
+var historyCmdIP = historyCmd.Flag("ip", "Host to print the history of.").Required().String()
+
+func historyCommand() {
+	printHistory(*dbPath, *historyCmdIP)
+}
+
+func printHistory(path, ip string) {
+	history, err := loadHistory(path)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	historyRender(history.entries(ip))
+}
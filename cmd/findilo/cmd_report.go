@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"sort"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+// latestEntries returns the latest entry of every host, sorted by address.
+func latestEntries(history *History) []HistoryEntry {
+	entries := []HistoryEntry{}
+	for _, entry := range history.Hosts {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		k1, k2 := scanner.IPKey(entries[i].IP), scanner.IPKey(entries[j].IP)
+		return bytes.Compare(k1[:], k2[:]) < 0
+	})
+	return entries
+}
+
+// reportCommand prints the latest entry of every host in the history
+// without scanning.
+func reportCommand() {
+	history, err := loadHistory(*dbPath)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	historyRender(latestEntries(history))
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLatestEntriesByAddress(t *testing.T) {
+	h := &History{Hosts: map[string]HistoryEntry{}}
+	for _, ip := range []string{"10.0.0.10", "10.0.0.9", "10.0.1.1", "10.0.0.100"} {
+		h.Hosts[ip] = HistoryEntry{IP: ip}
+	}
+	got := []string{}
+	for _, entry := range latestEntries(h) {
+		got = append(got, entry.IP)
+	}
+	want := []string{"10.0.0.9", "10.0.0.10", "10.0.0.100", "10.0.1.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("latestEntries order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// The addresses to scan, set by the targetFlags of scan and serve.
+var (
+	inputLists   = new([]string)
+	excludes     = new([]string)
+	excludeFiles = new([]string)
+	resolveAll   = new(bool)
+	networks     = new([]string)
+)
+
+func init() {
+	targetFlags(scanCmd)
+	targetFlags(serveCmd)
+}
+
+// targetFlags adds the flags and the argument that select the addresses
+// to scan to cmd.
+func targetFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("input-list", "Read networks from this file, one per line, # starts a comment (repeatable, also -iL).").PlaceHolder("FILE").StringsVar(inputLists)
+	cmd.Flag("exclude", "Skip these addresses or networks, comma separated (repeatable).").PlaceHolder("NETWORK").StringsVar(excludes)
+	cmd.Flag("exclude-file", "Skip the networks listed in this file, one per line (repeatable).").PlaceHolder("FILE").StringsVar(excludeFiles)
+	cmd.Flag("resolve-all", "Scan every address of a hostname target, not only the first.").BoolVar(resolveAll)
+	cmd.Arg("network", "Scan network, format 10.0.0.0/24, 10.0.0.1, 10.0.0.10-120, fd00::/120 or a hostname, - reads them from stdin, one per line. Stdin is also read when no network is given and it is not a terminal.").HintOptions("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16").StringsVar(networks)
+}
+
+var (
+	stateFile  = scanCmd.Flag("state-file", "Save the scan progress to this file, to continue an interrupted scan with --resume.").PlaceHolder("FILE").String()
+	resumeFile = scanCmd.Flag("resume", "Continue the scan saved in this state file instead of scanning the networks.").PlaceHolder("FILE").String()
+)
+
+var failThreshold = scanCmd.Flag("fail-threshold", "Exit with 3 when more than this percent of the hosts that answered the probe could not be read.").Default("10").PlaceHolder("PERCENT").Int()
+
+var dryRun = scanCmd.Flag("dry-run", "Print the addresses that would be scanned and their count, then exit without sending anything.").Bool()
+
+// Exit codes of a scan, for scripts. Usage errors exit with 1, like any
+// other error before the scan.
+const (
+	exitNoHosts     = 2
+	exitFailures    = 3
+	exitInterrupted = 130
+)
+
+// scanCommand scans the networks and reports the results. It also runs
+// for a plain "findilo <network>".
+func scanCommand() {
+	if *historyIP != "" {
+		printHistory(*dbPath, *historyIP)
+		return
+	}
+	if *amplifierURL != "" {
+		ilo, err := requestAmplifier(&config, *amplifierURL, *amplifierToken, *amplifierQuery)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		report(ilo, nil)
+		return
+	}
+	dialLimiter = newRateLimiter(*rate)
+	defer dialLimiter.stop()
+	if *resumeFile != "" {
+		p, remaining, err := loadScanProgress(*resumeFile)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		if *stateFile != "" {
+			p.path = *stateFile
+		}
+		logger.Infof("resuming %s: %d of %d hosts left, %d found so far", *resumeFile, remaining.Count(), p.targets.Count(), len(p.prevHosts))
+		if *dryRun {
+			printTargets(remaining)
+		}
+		progress = p
+		ipNetParsed = remaining
+	} else {
+		ipNetParsed = scanTargets()
+		if *stateFile != "" {
+			progress = newScanProgress(*stateFile, ipNetParsed)
+		}
+	}
+	if *skipUnchanged {
+		if err := cache.load(*cacheFile); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr)
+	}
+	if *findSerial != "" {
+		lookupSerial(ipNetParsed, *findSerial)
+		return
+	}
+	// The first SIGINT or SIGTERM stops the scan and reports what was found
+	// so far; after it the default handling is restored, so a second one
+	// kills findilo without waiting for the requests in flight.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if *watchInterval > 0 {
+		watch(ctx, ipNetParsed, *watchInterval)
+		return
+	}
+	ilo, failed := runScan(ctx, ipNetParsed, !*hostsOnly)
+	report(ilo, failed)
+	if ctx.Err() != nil {
+		os.Exit(exitInterrupted)
+	}
+	if *metricsAddr != "" {
+		// Keep serving the results of the scan until interrupted.
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+	}
+	if code := scanExitCode(ilo, failed, config.FailThreshold); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// scanExitCode returns exitFailures when more than threshold percent of
+// the hosts that answered failed, exitNoHosts when no host was found and
+// 0 otherwise. A found host with a failed Redfish check counts as failed.
+func scanExitCode(ilo []ILOInfo, failed []ILOError, threshold int) int {
+	answered := map[string]bool{}
+	for _, info := range ilo {
+		answered[info.IP] = false
+	}
+	for _, e := range failed {
+		answered[e.IP] = true
+	}
+	bad := 0
+	for _, f := range answered {
+		if f {
+			bad++
+		}
+	}
+	if bad > 0 && bad*100 > threshold*len(answered) {
+		return exitFailures
+	}
+	if len(ilo) == 0 {
+		return exitNoHosts
+	}
+	return 0
+}
+
+// scanTargets collects the addresses to scan from the arguments, the
+// input lists and stdin, without the excluded ones and, with --arp or
+// --ping, without the ones that did not answer.
+func scanTargets() scanner.Targets {
+	if *autoNetworks {
+		nets, err := localNetworks(*autoMinPrefix)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		for _, n := range nets {
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "discovered network %s\n", n)
+			}
+		}
+		*networks = append(*networks, nets...)
+	}
+	args := *networks
+	*networks = nil
+	for _, arg := range args {
+		if arg != stdinArg {
+			*networks = append(*networks, arg)
+			continue
+		}
+		nets, err := readNetworks(os.Stdin)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		*networks = append(*networks, nets...)
+	}
+	for _, path := range *inputLists {
+		nets, err := readNetworksFile(path)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		*networks = append(*networks, nets...)
+	}
+	if len(*networks) == 0 && *autoNetworks {
+		kingpin.Fatalf("--auto found no private network of /%d or longer on the local interfaces", *autoMinPrefix)
+	}
+	if len(*networks) == 0 && !isTerminal(os.Stdin) {
+		nets, err := readNetworks(os.Stdin)
+		if err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+		*networks = nets
+	}
+	if len(*networks) == 0 {
+		kingpin.Fatalf("required argument 'network' not provided, try --help")
+	}
+	nets, err := resolveTargets(*networks, *resolveAll)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	nets, err = scanner.ExpandRanges(nets)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	nets, removed, err := scanner.AggregateCIDRs(nets)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if len(removed) > 0 {
+		logger.Debugf("redundant or merged CIDRs removed: %s", strings.Join(removed, ", "))
+	}
+	ips, err := scanner.NewTargets(nets)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	excluded, err := excludedNetworks(*excludes, *excludeFiles)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	targets := excludeAddresses(ips, excluded)
+	if *dryRun {
+		printTargets(targets)
+	}
+	if *arp {
+		alive, err := arpSweep(targets)
+		if err != nil {
+			logger.Warnf("ARP disabled, scanning all hosts: %v", err)
+		} else {
+			targets = alive
+		}
+	}
+	if *ping {
+		alive, err := pingSweep(targets)
+		if err != nil {
+			logger.Warnf("ping disabled, scanning all hosts: %v", err)
+		} else {
+			targets = scanner.TargetsOf(alive)
+		}
+	}
+	return targets
+}
+
+// printTargets prints the addresses one per line and their count to stderr
+// and exits, for --dry-run.
+func printTargets(targets scanner.Targets) {
+	w := bufio.NewWriter(os.Stdout)
+	targets.Each(func(ip string) bool {
+		fmt.Fprintln(w, ip)
+		return true
+	})
+	w.Flush()
+	fmt.Fprintf(os.Stderr, "%d addresses\n", targets.Count())
+	os.Exit(0)
+}
+
+// readNetworks reads networks in the format of the network argument, one
+// per line. Everything after a # is a comment, blank lines are skipped.
+func readNetworks(r io.Reader) ([]string, error) {
+	nets := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		nets = append(nets, line)
+	}
+	return nets, scanner.Err()
+}
+
+// readNetworksFile reads the networks of a file, or of stdin for "-".
+func readNetworksFile(path string) ([]string, error) {
+	if path == stdinArg {
+		return readNetworks(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	nets, err := readNetworks(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return nets, nil
+}
+
+// stdinArg replaces a "-" argument, which kingpin would reject as an
+// empty short flag.
+const stdinArg = "<stdin>"
+
+// rewriteArgs rewrites what kingpin cannot parse: the nmap style -iL,
+// which it would read as the short flags -i -L, becomes --input-list and
+// "-" for stdin becomes stdinArg.
+func rewriteArgs(args []string) []string {
+	res := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(res, args[i:]...)
+		}
+		if arg == "-" {
+			arg = stdinArg
+		} else if arg == "-iL" {
+			arg = "--input-list"
+		} else if strings.HasPrefix(arg, "-iL=") {
+			arg = "--input-list=" + strings.TrimPrefix(arg, "-iL=")
+		}
+		res = append(res, arg)
+	}
+	return res
+}
+
+// lookupSerial scans ips until it finds the host with the serial number
+// and prints it, or exits with 1 when no host has it.
+func lookupSerial(ips scanner.Targets, serial string) {
+	serial = strings.TrimSpace(serial)
+	match := func(info ILOInfo) bool {
+		return strings.EqualFold(strings.TrimSpace(info.Serial), serial)
+	}
+	ctx, cancel := withMaxDuration(context.Background())
+	defer cancel()
+	ilo, _ := scanRound(ctx, ips, true, match)
+	for _, info := range ilo {
+		if match(info) {
+			tableRender([]ILOInfo{info})
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "serial %s not found\n", serial)
+	os.Exit(1)
+}
+
+// excludedNetworks parses the --exclude and --exclude-file targets, which
+// use the same formats as the networks to scan.
+func excludedNetworks(excludes, files []string) ([]*net.IPNet, error) {
+	targets := []string{}
+	for _, e := range excludes {
+		for _, t := range strings.Split(e, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				targets = append(targets, t)
+			}
+		}
+	}
+	for _, path := range files {
+		nets, err := readNetworksFile(path)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, nets...)
+	}
+	targets, err := resolveTargets(targets, true)
+	if err != nil {
+		return nil, err
+	}
+	targets, err = scanner.ExpandRanges(targets)
+	if err != nil {
+		return nil, err
+	}
+	res := []*net.IPNet{}
+	for _, t := range targets {
+		_, ipnet, err := net.ParseCIDR(scanner.HostCIDR(t))
+		if err != nil {
+			return nil, fmt.Errorf("--exclude: %v", err)
+		}
+		res = append(res, ipnet)
+	}
+	return res, nil
+}
+
+// excludeAddresses returns the ips that are in none of the excluded
+// networks.
+func excludeAddresses(ips scanner.Targets, excluded []*net.IPNet) scanner.Targets {
+	if len(excluded) == 0 {
+		return ips
+	}
+	res := ips.Exclude(excluded)
+	logger.Debugf("excluded %d addresses", ips.Count()-res.Count())
+	return res
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	serveListen   = serveCmd.Flag("listen", "Address to serve the results on.").Default(":9125").String()
+	serveInterval = serveCmd.Flag("interval", "Rescan the networks at this interval.").Default("1h").Duration()
+)
+
+// serveCommand scans the networks every --interval and serves the last
+// completed scan: Prometheus metrics on /metrics and the --output json
+// document on /hosts.
+func serveCommand() {
+	if *serveInterval <= 0 {
+		kingpin.Fatalf("--interval must be positive")
+	}
+	dialLimiter = newRateLimiter(*rate)
+	defer dialLimiter.stop()
+	ips := scanTargets()
+	ln, err := net.Listen("tcp", *serveListen)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.HandleFunc("/hosts", metrics.serveReport)
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			logger.Errorf("serve: %v", err)
+		}
+	}()
+	if !*quiet {
+		fmt.Fprintf(os.Stderr, "serving on %s, rescanning every %s\n", ln.Addr(), *serveInterval)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		ilo, failed := runScan(ctx, ips, false)
+		if ctx.Err() != nil {
+			return
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format("15:04:05"), summaryLine(ilo, failed))
+		}
+		select {
+		case <-time.After(*serveInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// fishCompletionTemplate asks the kingpin completion engine for flag
+// values and arguments, and lists the visible flags with their help.
+var fishCompletionTemplate = `function __{{.App.Name}}_complete
+    set -l tokens (commandline -opc)
+    {{.App.Name}} --completion-bash $tokens[2..-1] (commandline -ct)
+end
+complete -c {{.App.Name}} -f -a '(__{{.App.Name}}_complete)'
+{{range .App.Flags}}\
+{{if not .Hidden}}\
+complete -c {{$.App.Name}} -l {{.Name}}{{if not .IsBoolFlag}} -r{{end}} -d "{{.Help}}"
+{{end}}\
+{{end}}\
+`
+
+var completionShell *string
+
+var completionCmdShell = completionCmd.Arg("shell", "Shell to write the script for.").Required().Enum("bash", "zsh", "fish")
+
+func init() {
+	// The flag the completion command was added as.
+	completionShell = kingpin.Flag("completion", "Same as the completion command.").Hidden().
+		PreAction(generateCompletion).Enum("bash", "zsh", "fish")
+}
+
+func generateCompletion(c *kingpin.ParseContext) error {
+	if err := writeCompletion(c, *completionShell); err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
+
+// completionCommand writes the completion script of the shell. The script
+// calls findilo back to complete flags, their values and arguments.
+func completionCommand() {
+	c, err := kingpin.CommandLine.ParseContext(nil)
+	if err == nil {
+		err = writeCompletion(c, *completionCmdShell)
+	}
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+func writeCompletion(c *kingpin.ParseContext, shell string) error {
+	templates := map[string]string{
+		"bash": kingpin.BashCompletionTemplate,
+		"zsh":  kingpin.ZshCompletionTemplate,
+		"fish": fishCompletionTemplate,
+	}
+	app := kingpin.CommandLine
+	app.Writer(os.Stdout)
+	return app.UsageForContextWithTemplate(c, 2, templates[shell])
+}
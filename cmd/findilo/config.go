@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// config is the Config of the command line, set once the flags are parsed.
+var config Config
+
+// outputSet reports whether --output was given on the command line.
+var outputSet bool
+
+func markOutputSet(*kingpin.ParseContext) error {
+	outputSet = true
+	return nil
+}
+
+// Config holds the command line settings that validateFlags checks and
+// that newHTTPClient applies.
+type Config struct {
+	Username      string
+	Password      string
+	Output        string
+	OutputSet     bool
+	HostsOnly     bool
+	FWDiff        bool
+	WatchInterval time.Duration
+	Workers       int
+	Proxy         string
+	HTTPTimeout   time.Duration
+	ConnTimeout   time.Duration
+	MaxDuration   time.Duration
+	AutoMinPrefix int
+	Retries       int
+	FailThreshold int
+	ProbePort     int
+	AltPort       int
+	MultiPort     bool
+	HTTPSBanner   bool
+	WithSwitches  bool
+	MaxRespTime   time.Duration
+	SkipUnchanged bool
+	VerifyDNS     bool
+	DiscoverAPI   bool
+	DefaultCreds  bool
+	Federation    bool
+	CertCheck     bool
+	CollectNIC    bool
+	RequireNIC    bool
+	CollectLED    bool
+	CollectPower  bool
+	SessionCheck  bool
+	SessionMax    int
+	SessionSet    int
+	CollectSSA    bool
+	RaidAlert     bool
+	LEDBlinking   bool
+	SkipDNSBad    bool
+	ILONameFilter string
+	ServerFilter  string
+	InsecureTLS   bool
+	Rate          int
+	OTelEndpoint  string
+	Amplifier     bool
+	AmpToken      string
+	RedfishFlags  []string
+	SetFlags      []string
+}
+
+func init() {
+	// The names the connect timeout and the worker count were added under.
+	kingpin.Flag("timeout-connect", "Same as --connect-timeout.").Hidden().DurationVar(connTimeout)
+	kingpin.Flag("workers", "Same as --concurrency.").Hidden().IntVar(workers)
+}
+
+// configFromFlags collects the parsed flags into a Config.
+func configFromFlags() Config {
+	cfg := Config{
+		Username:      *username,
+		Password:      *password,
+		Output:        *output,
+		OutputSet:     outputSet,
+		HostsOnly:     *hostsOnly,
+		FWDiff:        *fwDiff,
+		WatchInterval: *watchInterval,
+		Workers:       *workers,
+		Proxy:         *proxy,
+		HTTPTimeout:   *httpTimeout,
+		ConnTimeout:   *connTimeout,
+		MaxDuration:   *maxDuration,
+		AutoMinPrefix: *autoMinPrefix,
+		Retries:       *retries,
+		FailThreshold: *failThreshold,
+		ProbePort:     *probePort,
+		AltPort:       *altPort,
+		MultiPort:     *multiPort,
+		HTTPSBanner:   *httpsBanner,
+		WithSwitches:  *withSwitches,
+		MaxRespTime:   *maxRespTime,
+		SkipUnchanged: *skipUnchanged,
+		VerifyDNS:     *verifyDNS,
+		DiscoverAPI:   *discoverAPI,
+		DefaultCreds:  *defaultCreds,
+		Federation:    *federation,
+		CertCheck:     *certCheck,
+		CollectNIC:    *collectNICTeam,
+		RequireNIC:    *requireNICTeam,
+		CollectLED:    *collectLED,
+		CollectPower:  *collectPower,
+		SessionCheck:  *sessionCheck,
+		SessionMax:    *sessionMax,
+		SessionSet:    *sessionSet,
+		CollectSSA:    *collectSSA,
+		RaidAlert:     *raidAlert,
+		LEDBlinking:   *ledBlinking,
+		SkipDNSBad:    *skipDNSBad,
+		ILONameFilter: *iloNameFilter,
+		ServerFilter:  *serverFilter,
+		Rate:          *rate,
+		OTelEndpoint:  *otelEndpoint,
+		Amplifier:     *amplifierURL != "",
+		AmpToken:      *amplifierToken,
+	}
+	redfish := []struct {
+		name string
+		set  bool
+	}{
+		{"--collect-nic-team", *collectNICTeam},
+		{"--require-nic-team", *requireNICTeam},
+		{"--collect-led", *collectLED},
+		{"--filter-led-blinking", *ledBlinking},
+		{"--collect-power", *collectPower},
+		{"--check-session-timeout", *sessionCheck},
+		{"--require-session-timeout-max", *sessionMax != 0},
+		{"--collect-ssa", *collectSSA},
+		{"--alert-raid-degraded", *raidAlert},
+		{"--federation", *federation},
+	}
+	for _, f := range redfish {
+		if f.set {
+			cfg.RedfishFlags = append(cfg.RedfishFlags, f.name)
+		}
+	}
+	if *sessionSet != 0 {
+		cfg.SetFlags = append(cfg.SetFlags, "--set-session-timeout")
+	}
+	return cfg
+}
+
+// flagErrors lists every conflicting flag combination.
+type flagErrors []string
+
+func (e flagErrors) Error() string {
+	return "invalid flags:\n  " + strings.Join(e, "\n  ")
+}
+
+// validateFlags checks the flag combinations before any network I/O is
+// done and returns all violations at once.
+func validateFlags(cfg Config) error {
+	errs := flagErrors{}
+	for _, f := range cfg.SetFlags {
+		if cfg.Username == "" {
+			errs = append(errs, f+" requires --username")
+		}
+	}
+	for _, f := range cfg.RedfishFlags {
+		if cfg.Username == "" {
+			errs = append(errs, f+" requires --username")
+		}
+	}
+	if cfg.Password != "" && cfg.Username == "" {
+		errs = append(errs, "--password requires --username")
+	}
+	if cfg.FWDiff && cfg.Output != "table" {
+		errs = append(errs, fmt.Sprintf("--diff cannot be used with --output %s", cfg.Output))
+	}
+	if cfg.HostsOnly && cfg.OutputSet {
+		errs = append(errs, "--hosts-only cannot be used with --output")
+	}
+	if cfg.FWDiff && cfg.WatchInterval > 0 {
+		errs = append(errs, "--diff cannot be used with --watch")
+	}
+	if cfg.Workers < 1 {
+		errs = append(errs, "--concurrency must be at least 1")
+	}
+	if cfg.ProbePort < 1 || cfg.ProbePort > 65535 {
+		errs = append(errs, fmt.Sprintf("--port %d is not a TCP port", cfg.ProbePort))
+	}
+	if cfg.AltPort < 0 || cfg.AltPort > 65535 {
+		errs = append(errs, fmt.Sprintf("--alt-port %d is not a TCP port", cfg.AltPort))
+	}
+	for _, f := range []struct{ name, pattern string }{
+		{"--iloname-filter", cfg.ILONameFilter},
+		{"--servername-filter", cfg.ServerFilter},
+	} {
+		if _, err := path.Match(f.pattern, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("%s %q is not a valid glob pattern", f.name, f.pattern))
+		}
+	}
+	if cfg.ConnTimeout <= 0 {
+		errs = append(errs, "--connect-timeout must be positive")
+	}
+	if cfg.HTTPTimeout <= 0 {
+		errs = append(errs, "--http-timeout must be positive")
+	}
+	if cfg.AutoMinPrefix < 0 || cfg.AutoMinPrefix > 32 {
+		errs = append(errs, "--auto-min-prefix must be between 0 and 32")
+	}
+	if cfg.MaxDuration < 0 {
+		errs = append(errs, "--max-duration must not be negative")
+	}
+	if cfg.Retries < 0 {
+		errs = append(errs, "--retries must not be negative")
+	}
+	if cfg.FailThreshold < 0 || cfg.FailThreshold > 100 {
+		errs = append(errs, "--fail-threshold must be between 0 and 100")
+	}
+	if cfg.Rate < 0 || cfg.Rate > maxRate {
+		errs = append(errs, fmt.Sprintf("--rate must be between 0 and %d", maxRate))
+	}
+	if e := cfg.OTelEndpoint; e != "" && !isGRPCEndpoint(e) && !strings.HasPrefix(e, "http://") && !strings.HasPrefix(e, "https://") {
+		errs = append(errs, "--otel-endpoint must be a grpc:// or grpcs:// OTLP/gRPC endpoint, or an http:// or https:// OTLP/HTTP one")
+	}
+	if cfg.Proxy != "" {
+		if u, err := url.Parse(cfg.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("--proxy %q is not a URL like http://proxy:3128", cfg.Proxy))
+		}
+	}
+	if cfg.Amplifier && cfg.AmpToken == "" {
+		errs = append(errs, "--amplifier-url requires --amplifier-token")
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// testFlagCombinations validates the flags and exits.
+func testFlagCombinations(cfg Config) {
+	if err := validateFlags(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("flag combinations OK")
+	os.Exit(0)
+}
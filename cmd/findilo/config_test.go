@@ -0,0 +1,15 @@
+package main
+
+import "time"
+
+// validConfig returns a Config that passes validateFlags.
+func validConfig() Config {
+	return Config{
+		Output:      "table",
+		Workers:     100,
+		ProbePort:   17988,
+		AltPort:     443,
+		ConnTimeout: 250 * time.Millisecond,
+		HTTPTimeout: 5 * time.Second,
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = "~/.findilo.yaml"
+
+// configProfiles is the setting that holds the named profiles of the
+// config file, selected with --profile.
+const configProfiles = "profiles"
+
+// configCommands are the commands whose own flags can be set in the config
+// file. A flag of several commands is set for each of them.
+var configCommands = []string{"scan", "serve", "export"}
+
+// configSkipFlags are flags that make no sense in the config file.
+var configSkipFlags = map[string]bool{
+	"help":                   true,
+	"config":                 true,
+	"profile":                true,
+	"init":                   true,
+	"generate-manpage":       true,
+	"completion":             true,
+	"test-flag-combinations": true,
+	"history":                true,
+}
+
+// configAliases maps the hidden old names of renamed flags to the flag
+// they set. The default of a hidden alias has no effect.
+var configAliases = map[string]string{
+	"timeout-connect": "connect-timeout",
+	"workers":         "concurrency",
+	"xlsx":            "export-xlsx",
+}
+
+// configFileArg finds the --config flag before kingpin parses the command
+// line, because the config file provides the flag defaults.
+func configFileArg(args []string) (string, bool) {
+	if path, ok := flagArg(args, "--config"); ok {
+		return path, true
+	}
+	return defaultConfigFile, false
+}
+
+// flagArg returns the value of the flag name on the command line, for the
+// flags that are needed before kingpin parses it.
+func flagArg(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return "", false
+		case arg == name && i+1 < len(args):
+			return args[i+1], true
+		case strings.HasPrefix(arg, name+"="):
+			return strings.TrimPrefix(arg, name+"="), true
+		}
+	}
+	return "", false
+}
+
+func hasArg(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyConfigFile sets the values of the YAML config file as flag
+// defaults, so flags on the command line still override them. The values
+// of a non-empty profile override the ones at the top of the file. A
+// missing file is only an error when it was given explicitly or a profile
+// is selected.
+func applyConfigFile(app *kingpin.Application, path string, explicit bool, profile string) error {
+	values, err := readConfigFile(path)
+	if os.IsNotExist(err) && !explicit && profile == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	profiles, err := configProfileValues(path, values[configProfiles])
+	if err != nil {
+		return err
+	}
+	delete(values, configProfiles)
+	if err := applyConfigValues(app, path, values); err != nil {
+		return err
+	}
+	if profile == "" {
+		return nil
+	}
+	p, ok := profiles[profile]
+	if !ok {
+		return fmt.Errorf("%s: no profile %q", path, profile)
+	}
+	return applyConfigValues(app, fmt.Sprintf("%s: profile %s", path, profile), p)
+}
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
+
+// configProfileValues checks that the profiles setting maps names to
+// settings.
+func configProfileValues(path string, value interface{}) (map[string]map[string]interface{}, error) {
+	profiles := map[string]map[string]interface{}{}
+	if value == nil {
+		return profiles, nil
+	}
+	named, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: %s must map profile names to settings", path, configProfiles)
+	}
+	for name, v := range named {
+		settings, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: profile %s must be a map of settings", path, name)
+		}
+		profiles[name] = settings
+	}
+	return profiles, nil
+}
+
+// profileNames lists the profiles of the config file for the completion
+// of --profile.
+func profileNames() []string {
+	path, _ := configFileArg(os.Args[1:])
+	values, err := readConfigFile(path)
+	if err != nil {
+		return nil
+	}
+	profiles, err := configProfileValues(path, values[configProfiles])
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyConfigValues sets the settings of the config file, or of one of its
+// profiles, as flag defaults. where names them in errors.
+func applyConfigValues(app *kingpin.Application, where string, values map[string]interface{}) error {
+	for key, value := range values {
+		var defaults []string
+		if list, ok := value.([]interface{}); ok {
+			for _, v := range list {
+				defaults = append(defaults, fmt.Sprint(v))
+			}
+		} else {
+			defaults = []string{fmt.Sprint(value)}
+		}
+		if key == "network" {
+			app.GetCommand("scan").GetArg("network").Default(defaults...)
+			app.GetCommand("serve").GetArg("network").Default(defaults...)
+			continue
+		}
+		if name, ok := configAliases[key]; ok {
+			key = name
+		}
+		flags := []*kingpin.FlagClause{}
+		if flag := app.GetFlag(key); flag != nil {
+			flags = append(flags, flag)
+		}
+		for _, name := range configCommands {
+			if flag := app.GetCommand(name).GetFlag(key); flag != nil {
+				flags = append(flags, flag)
+			}
+		}
+		if len(flags) == 0 || configSkipFlags[key] {
+			return fmt.Errorf("%s: unknown setting %q", where, key)
+		}
+		for _, flag := range flags {
+			flag.Default(defaults...)
+		}
+	}
+	return nil
+}
+
+// exampleConfig lists every flag with its help text and default value,
+// commented out.
+func exampleConfig(app *kingpin.Application) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# findilo configuration. Flags on the command line override these values.")
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "# Networks scanned when none are given.")
+	fmt.Fprintln(buf, "# network: [10.0.0.0/24]")
+	flags := app.Model().Flags
+	seen := map[string]bool{}
+	for _, name := range configCommands {
+		for _, f := range app.GetCommand(name).Model().Flags {
+			if !seen[f.Name] && app.GetFlag(f.Name) == nil {
+				seen[f.Name] = true
+				flags = append(flags, f)
+			}
+		}
+	}
+	for _, f := range flags {
+		if f.Hidden || configSkipFlags[f.Name] {
+			continue
+		}
+		value := "\"\""
+		switch {
+		case len(f.Default) > 0:
+			value = strings.Join(f.Default, ", ")
+		case f.IsBoolFlag():
+			value = "false"
+		}
+		fmt.Fprintln(buf, "")
+		fmt.Fprintf(buf, "# %s\n", f.Help)
+		fmt.Fprintf(buf, "# %s: %s\n", f.Name, value)
+	}
+	fmt.Fprintln(buf, "")
+	fmt.Fprintln(buf, "# Named profiles, selected with --profile, override the values above.")
+	fmt.Fprintln(buf, "# profiles:")
+	fmt.Fprintln(buf, "#   dc1:")
+	fmt.Fprintln(buf, "#     network: [10.1.0.0/16]")
+	fmt.Fprintln(buf, "#     rate: 500")
+	return buf.Bytes()
+}
+
+// writeExampleConfig creates the config file with exampleConfig and exits.
+// An existing file is never overwritten.
+func writeExampleConfig(app *kingpin.Application, path string) {
+	path = expandHome(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if _, err := f.Write(exampleConfig(app)); err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %s\n", path)
+}
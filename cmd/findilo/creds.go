@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCredsPause separates login attempts on the same host.
+const defaultCredsPause = time.Second
+
+// defaultCredentials are factory logins. iLO locks an account after three
+// failed logins by default, so the list must stay shorter than that.
+func defaultCredentials(info *ILOInfo) [][2]string {
+	return [][2]string{
+		{"Administrator", strings.TrimSpace(info.Serial)},
+		{"Admin", "Admin"},
+	}
+}
+
+// credsChecked holds the result of the check of every iLO this process
+// tried the factory logins on, so that the rounds of --watch and serve do
+// not repeat the failed logins and lock the accounts out.
+var credsChecked = struct {
+	sync.Mutex
+	hosts map[string]bool
+}{hosts: map[string]bool{}}
+
+// credsKey names an iLO in credsChecked: by its serial number, which stays
+// when the address changes, or by ip without one.
+func credsKey(ip string, info *ILOInfo) string {
+	if serial := strings.TrimSpace(info.Serial); serial != "" && serial != notAvailable {
+		return "serial " + serial
+	}
+	return "ip " + ip
+}
+
+func markDefaultCreds(info *ILOInfo) {
+	info.DefaultCreds = true
+	info.Warnings = append(info.Warnings, "default credentials")
+}
+
+// checkDefaultCreds tries the factory logins on the session endpoint and
+// sets DefaultCreds when one is accepted. Each iLO is tried once per
+// process; later calls reuse the first result.
+func checkDefaultCreds(ctx context.Context, cfg *Config, ip string, info *ILOInfo) error {
+	key := credsKey(ip, info)
+	credsChecked.Lock()
+	found, checked := credsChecked.hosts[key]
+	if !checked {
+		// Taken before the first login, so a canceled check is not
+		// tried again either.
+		credsChecked.hosts[key] = false
+	}
+	credsChecked.Unlock()
+	if checked {
+		logger.Debugf("%s: default logins already tried", ip)
+		if found {
+			markDefaultCreds(info)
+		}
+		return nil
+	}
+
+	path := info.sessionsPath
+	if path == "" {
+		path = defaultSessionsPath
+	}
+	for i, cred := range defaultCredentials(info) {
+		if i > 0 {
+			select {
+			case <-time.After(defaultCredsPause):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		token, location, err := createSession(ctx, cfg, ip, path, cred[0], cred[1])
+		if err != nil {
+			logger.Debugf("%s: default login %s: %v", ip, cred[0], err)
+			continue
+		}
+		markDefaultCreds(info)
+		credsChecked.Lock()
+		credsChecked.hosts[key] = true
+		credsChecked.Unlock()
+		return deleteSession(ctx, cfg, ip, location, token)
+	}
+	return nil
+}
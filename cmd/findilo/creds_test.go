@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// connectProxy is an HTTP proxy that tunnels every CONNECT to target, so
+// that requests to https://<any iLO>/ reach a test server.
+func connectProxy(t *testing.T, target string) *httptest.Server {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		go func() {
+			io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestDefaultCredsOncePerProcess(t *testing.T) {
+	for _, accept := range []bool{false, true} {
+		credsChecked.hosts = map[string]bool{}
+		var logins int32
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" && r.URL.Path == defaultSessionsPath {
+				atomic.AddInt32(&logins, 1)
+				if !accept {
+					http.Error(w, "bad login", http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("X-Auth-Token", "token")
+				w.WriteHeader(http.StatusCreated)
+			}
+		}))
+		cfg := validConfig()
+		cfg.DefaultCreds = true
+		cfg.Proxy = connectProxy(t, srv.Listener.Addr().String()).URL
+		f := iloFingerprinter{cfg: &cfg}
+
+		want := []int32{2, 2}
+		if accept {
+			want = []int32{1, 1}
+		}
+		for round := range want {
+			info := &ILOInfo{IP: "10.0.0.1", HW: "iLO 5", Serial: "CZ2D1234AB"}
+			if err := f.Enrich(context.Background(), info.device()); err != nil {
+				t.Errorf("accept %v, round %d: %v", accept, round+1, err)
+			}
+			if n := atomic.LoadInt32(&logins); n != want[round] {
+				t.Errorf("accept %v: %d logins after round %d, want %d", accept, n, round+1, want[round])
+			}
+			if info.DefaultCreds != accept || (accept && !strings.Contains(strings.Join(info.Warnings, ","), "default credentials")) {
+				t.Errorf("accept %v, round %d: DefaultCreds %v, warnings %v", accept, round+1, info.DefaultCreds, info.Warnings)
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestCredsKey(t *testing.T) {
+	for _, tt := range []struct {
+		serial, want string
+	}{
+		{"CZ2D1234AB ", "serial CZ2D1234AB"},
+		{"", "ip 10.0.0.1"},
+		{notAvailable, "ip 10.0.0.1"},
+	} {
+		if got := credsKey("10.0.0.1", &ILOInfo{Serial: tt.serial}); got != tt.want {
+			t.Errorf("credsKey(serial %q) = %q, want %q", tt.serial, got, tt.want)
+		}
+	}
+}
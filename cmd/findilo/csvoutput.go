@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+)
+
+// writeCSV writes the table columns as CSV with a header row.
+func writeCSV(w io.Writer, ilo []ILOInfo) error {
+	header, rows := tableRows(ilo)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// writeCSVFile writes the table columns as CSV to path.
+func writeCSVFile(path string, ilo []ILOInfo) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeCSV(f, ilo); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
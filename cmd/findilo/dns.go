@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+func isDNSNotFound(err error) bool {
+	dnsErr, ok := err.(*net.DNSError)
+	return ok && dnsErr.IsNotFound
+}
+
+// checkDNS looks up the iLO name forward and the IP in reverse and flags
+// the host when the two do not agree. Missing records are a mismatch, not
+// an error.
+func checkDNS(info *ILOInfo) error {
+	names, err := net.LookupAddr(info.IP)
+	if err != nil && !isDNSNotFound(err) {
+		return err
+	}
+	if len(names) > 0 {
+		info.FQDN = strings.TrimSuffix(names[0], ".")
+	}
+	if info.IloName != "" && info.IloName != notAvailable {
+		addrs, err := net.LookupHost(info.IloName)
+		if err != nil && !isDNSNotFound(err) {
+			return err
+		}
+		for _, addr := range addrs {
+			if net.ParseIP(addr).Equal(net.ParseIP(info.IP)) {
+				info.ForwardDNSMatch = true
+				break
+			}
+		}
+	}
+	info.DNSMismatch = !info.ForwardDNSMatch || !reverseMatches(names, info.IloName)
+	if info.DNSMismatch {
+		info.Warnings = append(info.Warnings, "DNS mismatch")
+	}
+	return nil
+}
+
+// reverseMatches reports whether a PTR name is the iLO name, either fully
+// qualified or as its first label.
+func reverseMatches(names []string, iloName string) bool {
+	iloName = strings.TrimSuffix(iloName, ".")
+	for _, name := range names {
+		name = strings.TrimSuffix(name, ".")
+		if strings.EqualFold(name, iloName) || strings.EqualFold(strings.SplitN(name, ".", 2)[0], iloName) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterDNSMismatch(ilo []ILOInfo) []ILOInfo {
+	res := []ILOInfo{}
+	for _, info := range ilo {
+		if !info.DNSMismatch {
+			res = append(res, info)
+		}
+	}
+	return res
+}
+
+func dnsString(info ILOInfo) string {
+	if info.DNSMismatch {
+		return info.FQDN + " !"
+	}
+	return info.FQDN
+}
+
+// isAddressTarget reports whether a target is an address, a CIDR or a
+// range rather than a hostname.
+func isAddressTarget(target string) bool {
+	if i := strings.IndexAny(target, "/-"); i >= 0 {
+		target = target[:i]
+	}
+	return net.ParseIP(target) != nil
+}
+
+// resolveTargets replaces the hostnames among targets by their address,
+// or by all of their addresses with all. Names that do not resolve are
+// logged and skipped.
+func resolveTargets(targets []string, all bool) ([]string, error) {
+	res := []string{}
+	for _, target := range targets {
+		if isAddressTarget(target) {
+			res = append(res, target)
+			continue
+		}
+		if strings.ContainsAny(target, "*?[") {
+			return nil, fmt.Errorf("%s: DNS cannot expand wildcards, list the names or use a network", target)
+		}
+		addrs, err := net.LookupHost(target)
+		if err != nil {
+			logger.Errorf("%s: %v", target, err)
+			continue
+		}
+		if !all {
+			addrs = addrs[:1]
+		}
+		logger.Debugf("%s resolves to %s", target, strings.Join(addrs, ", "))
+		res = append(res, addrs...)
+	}
+	return res, nil
+}
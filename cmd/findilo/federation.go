@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+const federationPath = "/rest/v1/Managers/1/Federation"
+
+// Federation lists the peers of the iLO federation groups of a manager.
+type Federation struct {
+	Peers []struct {
+		ManagerIPAddress string `json:"ManagerIPAddress"`
+		IPAddress        string `json:"IPAddress"`
+	} `json:"Peers"`
+}
+
+// requestFederationPeers returns the IPs of the federation peers that the
+// iLO knows about.
+func requestFederationPeers(ctx context.Context, cfg *Config, ip, sessionKey string) ([]string, error) {
+	url := hpilo.URL("https", ip, 0, federationPath)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Auth-Token", sessionKey)
+	resp, err := iloClient(cfg).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logResponse(resp, raw)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", federationPath, resp.Status)
+	}
+	fed := &Federation{}
+	if err := json.Unmarshal(raw, fed); err != nil {
+		return nil, err
+	}
+	peers := []string{}
+	for _, p := range fed.Peers {
+		addr := p.ManagerIPAddress
+		if addr == "" {
+			addr = p.IPAddress
+		}
+		if net.ParseIP(addr) != nil && addr != ip {
+			peers = append(peers, addr)
+		}
+	}
+	return peers, nil
+}
+
+// collectFederationPeers stores the federation peers of a host, using a
+// session that is closed again afterwards.
+func collectFederationPeers(ctx context.Context, cfg *Config, ip string, info *ILOInfo) error {
+	path := info.sessionsPath
+	if path == "" {
+		path = defaultSessionsPath
+	}
+	token, location, err := createSession(ctx, cfg, ip, path, cfg.Username, cfg.Password)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := deleteSession(ctx, cfg, ip, location, token); err != nil {
+			logger.Debugf("%s: %v", ip, err)
+		}
+	}()
+	info.FederationPeers, err = requestFederationPeers(ctx, cfg, ip, token)
+	return err
+}
+
+// unscannedPeers returns the federation peers of ilo that are neither in
+// scanned nor in seen and adds them to seen.
+func unscannedPeers(ilo []ILOInfo, scanned scanner.Targets, seen map[string]bool) []string {
+	next := []string{}
+	for _, info := range ilo {
+		for _, peer := range info.FederationPeers {
+			if !seen[peer] && !scanned.Contains(peer) {
+				seen[peer] = true
+				next = append(next, peer)
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,34 @@
+package main
+
+import "path"
+
+// filterResults applies the result filters of cfg. All filters must match
+// for a host to be kept.
+func filterResults(ilo []ILOInfo, cfg Config) []ILOInfo {
+	if cfg.LEDBlinking {
+		ilo = filterLEDBlinking(ilo)
+	}
+	if cfg.SkipDNSBad {
+		ilo = filterDNSMismatch(ilo)
+	}
+	if cfg.ILONameFilter == "" && cfg.ServerFilter == "" {
+		return ilo
+	}
+	res := []ILOInfo{}
+	for _, info := range ilo {
+		if globMatch(cfg.ILONameFilter, info.IloName) && globMatch(cfg.ServerFilter, info.ServerName) {
+			res = append(res, info)
+		}
+	}
+	return res
+}
+
+// globMatch reports whether name matches the path.Match pattern. An empty
+// pattern matches every name.
+func globMatch(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterResults(t *testing.T) {
+	ilo := []ILOInfo{
+		{IP: "10.0.0.1", IloName: "ilo-web1", ServerName: "web1"},
+		{IP: "10.0.0.2", IloName: "ilo-web2", ServerName: "db2"},
+		{IP: "10.0.0.3", IloName: "ilo-db1", ServerName: "db1"},
+		{IP: "10.0.0.4"},
+	}
+	tests := []struct {
+		name            string
+		iloname, server string
+		want            []string
+		invalid         bool
+	}{
+		{name: "no pattern", want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		{name: "iloname only", iloname: "ilo-web*", want: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "servername only", server: "db?", want: []string{"10.0.0.2", "10.0.0.3"}},
+		{name: "both", iloname: "ilo-web*", server: "db*", want: []string{"10.0.0.2"}},
+		{name: "empty names", iloname: "*", want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		{name: "malformed glob", iloname: "ilo-[web", want: []string{}, invalid: true},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.ILONameFilter, cfg.ServerFilter = tt.iloname, tt.server
+		got := []string{}
+		for _, info := range filterResults(ilo, cfg) {
+			got = append(got, info.IP)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: kept %v, want %v", tt.name, got, tt.want)
+		}
+		if err := validateFlags(cfg); (err != nil) != tt.invalid {
+			t.Errorf("%s: validateFlags = %v", tt.name, err)
+		}
+	}
+}
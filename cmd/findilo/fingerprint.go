@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hdhog/findilo/pkg/fingerprint"
+)
+
+// Orders of the fingerprinters. The iLO XML comes first, so that an iLO
+// is not taken for a plain HTTPS server.
+const (
+	orderILO    = 10
+	orderIPMI   = 20
+	orderBanner = 30
+	orderSwitch = 40
+)
+
+// device returns info as a fingerprint.Device, with info as its Details.
+func (info *ILOInfo) device() *fingerprint.Device {
+	return &fingerprint.Device{
+		IP:         info.IP,
+		Type:       info.DeviceType,
+		HW:         info.HW,
+		FW:         info.FW,
+		Model:      info.Model,
+		Serial:     info.Serial,
+		ServerName: info.ServerName,
+		Name:       info.IloName,
+		Details:    info,
+	}
+}
+
+// deviceInfo returns the host of dev: the ILOInfo of the fingerprinters
+// of this program, the common fields for any other.
+func deviceInfo(dev *fingerprint.Device) *ILOInfo {
+	if info, ok := dev.Details.(*ILOInfo); ok {
+		return info
+	}
+	return &ILOInfo{
+		IP:         dev.IP,
+		HW:         dev.HW,
+		Model:      dev.Model,
+		FW:         dev.FW,
+		Serial:     dev.Serial,
+		ServerName: dev.ServerName,
+		IloName:    dev.Name,
+		DeviceType: dev.Type,
+	}
+}
+
+// identifyHost identifies the host of probe and sends its failures to
+// errs. The fingerprinters of this program fail with ILOErrors; the
+// failures of others count as phaseIdentify.
+func identifyHost(ctx context.Context, probe *fingerprint.Host, host string, errs chan<- ILOError) *ILOInfo {
+	dev, step, err := probe.Identify(ctx)
+	for _, err := range joinedErrors(err) {
+		var e ILOError
+		if !errors.As(err, &e) {
+			e = ILOError{IP: host, Phase: phaseIdentify, Err: err}
+		}
+		hostError(errs, e.IP, e.Phase, e.Err)
+	}
+	if dev == nil {
+		return nil
+	}
+	logger.hostf(levelDebug, logFields{Host: host, Stage: "identify"}, "%s: identified by %s on port %d", host, step.Name, step.Port)
+	return deviceInfo(dev)
+}
+
+// joinedErrors returns the errors joined in err.
+func joinedErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range joined.Unwrap() {
+			errs = append(errs, joinedErrors(err)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestIdentifyReadsProbedPort(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xmldata":
+			w.Write([]byte(testRIMP))
+		case "/json/login_session":
+			w.Write([]byte(`{"server_name":"srv01","cn":"ilo-srv01"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := validConfig()
+	cfg.ProbePort = port
+	dev, final, err := iloFingerprinter{cfg: &cfg}.Identify(context.Background(), "127.0.0.1", port)
+	if dev == nil || !final || err != nil {
+		t.Fatalf("Identify on the --port = %+v, %v, %v", dev, final, err)
+	}
+	info := deviceInfo(dev)
+	if info.HW != "iLO 4" || info.ServerName != "srv01" {
+		t.Errorf("host = %+v", info)
+	}
+	if info.Port != 0 || !info.ILOOnHTTPS {
+		t.Errorf("Port = %d, ILOOnHTTPS = %v, want 0 and true", info.Port, info.ILOOnHTTPS)
+	}
+}
+
+func TestIdentifyFinalOnlyOnProbePort(t *testing.T) {
+	// The server drops every request, so the XML cannot be read.
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+	_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := validConfig()
+	f := iloFingerprinter{cfg: &cfg}
+	if _, final, err := f.Identify(context.Background(), "127.0.0.1", port); final || err != nil {
+		t.Errorf("a failed XML request on another port than --port gave final %v, error %v", final, err)
+	}
+	cfg.ProbePort = port
+	_, final, err := f.Identify(context.Background(), "127.0.0.1", port)
+	if !final {
+		t.Error("a failed XML request on the --port did not end the host")
+	}
+	if e, ok := err.(ILOError); !ok || e.Phase != phaseXML {
+		t.Errorf("error for the --port = %v, want a %s error", err, phaseXML)
+	}
+}
+
+func TestRequestPort(t *testing.T) {
+	for _, tt := range []struct{ port, want int }{
+		{iloPort, 0},
+		{httpPort, 0},
+		{httpsPort, httpsPort},
+		{8443, 8443},
+	} {
+		if got := requestPort(tt.port); got != tt.want {
+			t.Errorf("requestPort(%d) = %d, want %d", tt.port, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+type graphiteMetric struct {
+	name  string
+	value float64
+}
+
+// graphiteMetrics returns the numeric fields of a host. String fields have
+// no meaningful Graphite representation and are left out.
+func graphiteMetrics(info ILOInfo) []graphiteMetric {
+	metrics := []graphiteMetric{}
+	if fw, err := strconv.ParseFloat(info.FW, 64); err == nil {
+		metrics = append(metrics, graphiteMetric{"fw", fw})
+	} else {
+		logger.Debugf("graphite: %s: fw %q is not numeric", info.IP, info.FW)
+	}
+	if gen := hpilo.Generation(info.HW); gen > 0 {
+		metrics = append(metrics, graphiteMetric{"generation", float64(gen)})
+	}
+	if *collectNICTeam || *requireNICTeam {
+		team := 0.0
+		if info.NICTeamEnabled {
+			team = 1
+		}
+		metrics = append(metrics, graphiteMetric{"nic_team", team})
+	}
+	return metrics
+}
+
+// graphiteSend pushes the scan results to a Carbon server using the
+// plaintext protocol.
+func graphiteSend(addr string, ilo []ILOInfo, at time.Time) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for _, info := range ilo {
+		prefix := "findilo." + strings.Replace(info.IP, ".", "-", -1)
+		for _, m := range graphiteMetrics(info) {
+			_, err := fmt.Fprintf(conn, "%s.%s %s %d\n",
+				prefix, m.name, strconv.FormatFloat(m.value, 'f', -1, 64), at.Unix())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const userAgent = "findilo/1.0"
+
+// headerList is a repeatable Key:Value flag.
+type headerList struct {
+	http.Header
+}
+
+func (h *headerList) Set(value string) error {
+	kv := strings.SplitN(value, ":", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("expected Key:Value, got %q", value)
+	}
+	if h.Header == nil {
+		h.Header = http.Header{}
+	}
+	h.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	return nil
+}
+
+func (h *headerList) String() string {
+	return fmt.Sprint(h.Header)
+}
+
+func (h *headerList) IsCumulative() bool {
+	return true
+}
+
+var customHeaders = &headerList{}
+
+func init() {
+	kingpin.Flag("custom-header", "Add a Key:Value header to every HTTP request (repeatable).").
+		PlaceHolder("KEY:VALUE").SetValue(customHeaders)
+}
+
+// headerTransport sets the User-Agent and the --custom-header headers on
+// every request to an iLO.
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+	for key, values := range customHeaders.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
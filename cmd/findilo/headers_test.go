@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCustomHeadersOnlyForILOs(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	saved := customHeaders.Header
+	defer func() { customHeaders.Header = saved }()
+	customHeaders.Header = nil
+	if err := customHeaders.Set("X-WAF-Token: secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := validConfig()
+	for _, tt := range []struct {
+		name   string
+		client *http.Client
+		want   string
+	}{
+		{"ilo", iloClient(&cfg), "secret"},
+		{"other", newHTTPClient(cfg), ""},
+	} {
+		resp, err := tt.client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if v := got.Get("X-WAF-Token"); v != tt.want {
+			t.Errorf("%s client sent X-WAF-Token %q, want %q", tt.name, v, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+	"github.com/olekukonko/tablewriter"
+)
+
+// defaultHistoryFile is the default --db. The same JSON file used to be
+// named legacyHistoryFile; it is read until the new file is written.
+const (
+	defaultHistoryFile = "~/.findilo.json"
+	legacyHistoryFile  = "~/.findilo.db"
+)
+
+// historyEntriesPerHost is how many entries are kept per host; older ones
+// are dropped, so the file does not grow with every scan.
+const historyEntriesPerHost = 100
+
+// HistoryEntry is a single recorded scan result for one host.
+type HistoryEntry struct {
+	IP        string    `json:"ip"`
+	HW        string    `json:"hw"`
+	FW        string    `json:"fw"`
+	Serial    string    `json:"serial"`
+	Model     string    `json:"model"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// History keeps the latest entry per IP and the full log of entries.
+type History struct {
+	Hosts   map[string]HistoryEntry `json:"hosts"`
+	Entries []HistoryEntry          `json:"entries"`
+}
+
+// FWChange ...
+type FWChange struct {
+	Old HistoryEntry
+	New HistoryEntry
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+func loadHistory(path string) (*History, error) {
+	h := &History{Hosts: map[string]HistoryEntry{}}
+	raw, err := ioutil.ReadFile(expandHome(path))
+	if os.IsNotExist(err) && path == defaultHistoryFile {
+		raw, err = ioutil.ReadFile(expandHome(legacyHistoryFile))
+	}
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, h); err != nil {
+		return nil, err
+	}
+	if h.Hosts == nil {
+		h.Hosts = map[string]HistoryEntry{}
+	}
+	return h, nil
+}
+
+func (h *History) save(path string) error {
+	raw, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(expandHome(path), raw)
+}
+
+// writeFileAtomic writes raw to a temporary file next to path and renames
+// it over path, so an interrupted write does not leave a truncated file.
+func writeFileAtomic(path string, raw []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recordHistory adds the scan results to the history file and returns the
+// firmware changes. History errors are only logged, so that they do not
+// lose the results of the scan.
+func recordHistory(path string, ilo []ILOInfo, at time.Time) []FWChange {
+	history, err := loadHistory(path)
+	if err != nil {
+		logger.Errorf("history: %v", err)
+		return nil
+	}
+	changes := history.record(ilo, at)
+	if err := history.save(path); err != nil {
+		logger.Errorf("history: %v", err)
+	}
+	return changes
+}
+
+// record upserts the scan results by IP and returns hosts whose firmware
+// differs from the previously recorded scan.
+func (h *History) record(ilo []ILOInfo, at time.Time) []FWChange {
+	changes := []FWChange{}
+	for _, info := range ilo {
+		entry := HistoryEntry{
+			IP:        info.IP,
+			HW:        info.HW,
+			FW:        info.FW,
+			Serial:    info.Serial,
+			Model:     info.Model,
+			ScannedAt: at,
+		}
+		if prev, ok := h.Hosts[info.IP]; ok && prev.FW != entry.FW {
+			changes = append(changes, FWChange{Old: prev, New: entry})
+		}
+		h.Hosts[info.IP] = entry
+		h.Entries = append(h.Entries, entry)
+	}
+	h.compact()
+	sort.Slice(changes, func(i, j int) bool {
+		k1, k2 := scanner.IPKey(changes[i].New.IP), scanner.IPKey(changes[j].New.IP)
+		return bytes.Compare(k1[:], k2[:]) < 0
+	})
+	return changes
+}
+
+// compact drops the oldest entries of hosts with more than
+// historyEntriesPerHost entries.
+func (h *History) compact() {
+	count := map[string]int{}
+	keep := make([]bool, len(h.Entries))
+	dropped := false
+	for i := len(h.Entries) - 1; i >= 0; i-- {
+		ip := h.Entries[i].IP
+		count[ip]++
+		keep[i] = count[ip] <= historyEntriesPerHost
+		dropped = dropped || !keep[i]
+	}
+	if !dropped {
+		return
+	}
+	entries := h.Entries[:0]
+	for i, entry := range h.Entries {
+		if keep[i] {
+			entries = append(entries, entry)
+		}
+	}
+	h.Entries = entries
+}
+
+func (h *History) entries(ip string) []HistoryEntry {
+	res := []HistoryEntry{}
+	for _, entry := range h.Entries {
+		if entry.IP == ip {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+func fwChangesRender(changes []FWChange) {
+	data := [][]string{}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"IP", "HW", "S/N", "Model", "Old FW", "New FW", "Last scan"})
+	table.SetBorder(false)
+	for _, c := range changes {
+		data = append(data, []string{
+			c.New.IP,
+			c.New.HW,
+			c.New.Serial,
+			c.New.Model,
+			c.Old.FW,
+			c.New.FW,
+			c.Old.ScannedAt.Format(time.RFC3339),
+		})
+	}
+	table.AppendBulk(data)
+	fmt.Println("")
+	table.Render()
+}
+
+func historyRender(entries []HistoryEntry) {
+	data := [][]string{}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Scanned", "IP", "HW", "FW", "S/N", "Model"})
+	table.SetBorder(false)
+	for _, e := range entries {
+		data = append(data, []string{
+			e.ScannedAt.Format(time.RFC3339),
+			e.IP,
+			e.HW,
+			e.FW,
+			e.Serial,
+			e.Model,
+		})
+	}
+	table.AppendBulk(data)
+	table.Render()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecord(t *testing.T) {
+	h := &History{Hosts: map[string]HistoryEntry{}}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if changes := h.record([]ILOInfo{{IP: "10.0.0.1", FW: "2.50"}, {IP: "10.0.0.2", FW: "1.40"}}, at); len(changes) != 0 {
+		t.Errorf("first scan reported changes: %v", changes)
+	}
+	changes := h.record([]ILOInfo{{IP: "10.0.0.1", FW: "2.55"}, {IP: "10.0.0.2", FW: "1.40"}}, at.Add(time.Hour))
+	if len(changes) != 1 || changes[0].Old.FW != "2.50" || changes[0].New.FW != "2.55" {
+		t.Errorf("changes = %+v, want 10.0.0.1 from 2.50 to 2.55", changes)
+	}
+	if n := len(h.entries("10.0.0.1")); n != 2 {
+		t.Errorf("10.0.0.1 has %d entries, want 2", n)
+	}
+}
+
+func TestHistoryCompact(t *testing.T) {
+	h := &History{Hosts: map[string]HistoryEntry{}}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < historyEntriesPerHost+10; i++ {
+		h.record([]ILOInfo{{IP: "10.0.0.1", FW: "2.50"}}, at.Add(time.Duration(i)*time.Hour))
+	}
+	h.record([]ILOInfo{{IP: "10.0.0.2", FW: "1.40"}}, at)
+	entries := h.entries("10.0.0.1")
+	if len(entries) != historyEntriesPerHost {
+		t.Fatalf("10.0.0.1 has %d entries, want %d", len(entries), historyEntriesPerHost)
+	}
+	if want := at.Add(10 * time.Hour); !entries[0].ScannedAt.Equal(want) {
+		t.Errorf("oldest kept entry is from %v, want %v", entries[0].ScannedAt, want)
+	}
+	if n := len(h.entries("10.0.0.2")); n != 1 {
+		t.Errorf("10.0.0.2 has %d entries, want 1", n)
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	saved := logger.w
+	defer func() { logger.w = saved }()
+	logger.w = ioutil.Discard
+
+	path := filepath.Join(t.TempDir(), "findilo.json")
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recordHistory(path, []ILOInfo{{IP: "10.0.0.1", FW: "2.50"}}, at)
+	changes := recordHistory(path, []ILOInfo{{IP: "10.0.0.1", FW: "2.55"}}, at.Add(time.Hour))
+	if len(changes) != 1 {
+		t.Errorf("changes = %+v, want one", changes)
+	}
+	h, err := loadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.Entries); n != 2 {
+		t.Errorf("saved history has %d entries, want 2", n)
+	}
+}
+
+func TestRecordHistoryBrokenFile(t *testing.T) {
+	saved := logger.w
+	defer func() { logger.w = saved }()
+	logger.w = ioutil.Discard
+
+	path := filepath.Join(t.TempDir(), "findilo.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if changes := recordHistory(path, []ILOInfo{{IP: "10.0.0.1"}}, time.Now()); changes != nil {
+		t.Errorf("changes = %v, want none", changes)
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "{" {
+		t.Errorf("broken history file was overwritten with %q", raw)
+	}
+}
+
+func TestHistoryChangesByAddress(t *testing.T) {
+	h := &History{Hosts: map[string]HistoryEntry{}}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ips := []string{"10.0.0.10", "10.0.0.9", "10.0.0.100"}
+	for _, fw := range []string{"2.50", "2.55"} {
+		scan := []ILOInfo{}
+		for _, ip := range ips {
+			scan = append(scan, ILOInfo{IP: ip, FW: fw})
+		}
+		h.record(scan, at)
+	}
+	changes := h.record([]ILOInfo{{IP: "10.0.0.10", FW: "2.60"}, {IP: "10.0.0.9", FW: "2.60"}, {IP: "10.0.0.100", FW: "2.60"}}, at)
+	got := []string{}
+	for _, c := range changes {
+		got = append(got, c.New.IP)
+	}
+	if want := []string{"10.0.0.9", "10.0.0.10", "10.0.0.100"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes are ordered %v, want %v", got, want)
+	}
+}
+
+func TestLoadHistoryLegacyFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	old := &History{Hosts: map[string]HistoryEntry{"10.0.0.1": {IP: "10.0.0.1", FW: "2.50"}}}
+	if err := old.save(legacyHistoryFile); err != nil {
+		t.Fatal(err)
+	}
+	h, err := loadHistory(defaultHistoryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Hosts["10.0.0.1"].FW != "2.50" {
+		t.Errorf("default history = %+v, want the one of %s", h, legacyHistoryFile)
+	}
+	other, err := loadHistory(filepath.Join(home, "other.json"))
+	if err != nil || len(other.Hosts) != 0 {
+		t.Errorf("an explicit --db read %+v, %v, want an empty history", other, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// htmlPage is a standalone page: the styles and the sort script are
+// embedded, so the file can be shared as it is.
+var htmlPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>findilo report {{.Scanned}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .3em 1em; }
+dt { font-weight: bold; }
+dd { margin: 0; }
+table { border-collapse: collapse; margin-top: 1em; }
+th, td { border: 1px solid #ccc; padding: .3em .6em; text-align: left; white-space: nowrap; }
+th { background: #eee; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25b2"; }
+th.desc::after { content: " \25bc"; }
+tbody tr:nth-child(even) { background: #f8f8f8; }
+</style>
+</head>
+<body>
+<h1>findilo report</h1>
+<dl>
+<dt>Scanned</dt><dd>{{.Scanned}}</dd>
+<dt>Range</dt><dd>{{.Range}}</dd>
+<dt>Duration</dt><dd>{{.Duration}}</dd>
+<dt>Devices</dt><dd>{{.Summary}}</dd>
+</dl>
+<table>
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+<script>
+(function() {
+  var collator = new Intl.Collator(undefined, {numeric: true});
+  var ths = document.querySelectorAll("th");
+  ths.forEach(function(th, col) {
+    th.addEventListener("click", function() {
+      var asc = !th.classList.contains("asc");
+      ths.forEach(function(h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var tbody = document.querySelector("tbody");
+      var rows = Array.prototype.slice.call(tbody.rows);
+      rows.sort(function(a, b) {
+        var c = collator.compare(a.cells[col].textContent, b.cells[col].textContent);
+        return asc ? c : -c;
+      });
+      rows.forEach(function(r) { tbody.appendChild(r); });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
+
+type htmlReport struct {
+	Scanned  string
+	Range    string
+	Duration time.Duration
+	Summary  string
+	Header   []string
+	Rows     [][]string
+}
+
+// writeHTML writes the table as a standalone HTML page with a summary of
+// the scan.
+func writeHTML(w io.Writer, ilo []ILOInfo, failed []ILOError, nets []string, duration time.Duration, scanned time.Time) error {
+	header, rows := tableRows(ilo)
+	scanRange := strings.Join(nets, ", ")
+	if scanRange == "" {
+		scanRange = notAvailable
+	}
+	return htmlPage.Execute(w, htmlReport{
+		Scanned:  scanned.Format(time.RFC3339),
+		Range:    scanRange,
+		Duration: duration.Round(time.Millisecond),
+		Summary:  summaryLine(ilo, failed),
+		Header:   header,
+		Rows:     rows,
+	})
+}
+
+func writeHTMLFile(path string, ilo []ILOInfo, failed []ILOError, nets []string, duration time.Duration, scanned time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeHTML(f, ilo, failed, nets, duration, scanned); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenDatagramICMP opens an ICMP datagram socket, which Linux allows
+// without CAP_NET_RAW to the groups in net.ipv4.ping_group_range. The
+// kernel sets the echo ID to the port of the socket and only passes it the
+// replies to its own echoes.
+func listenDatagramICMP() (net.PacketConn, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+// listenDatagramICMP fails where unprivileged ICMP sockets are not
+// supported.
+func listenDatagramICMP() (net.PacketConn, error) {
+	return nil, errors.New("unprivileged ICMP sockets are only supported on Linux")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Scan phases in which a host can fail.
+const (
+	phaseXML        = "xml"
+	phaseParse      = "xml parse"
+	phaseServerName = "server name"
+	phaseRedfish    = "redfish"
+	phaseCert       = "cert"
+	phaseDNS        = "dns"
+	phaseIdentify   = "identify"
+)
+
+// ILOError is the failure of a single host in one scan phase.
+type ILOError struct {
+	IP    string
+	Phase string
+	Err   error
+}
+
+func (e ILOError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.IP, e.Phase, e.Err)
+}
+
+// Causes of a failure, from errorCause.
+const (
+	causeRefused = "connection refused"
+	causeReset   = "connection reset"
+	causeTimeout = "timeout"
+	causeTLS     = "tls"
+	causeParse   = "parse error"
+	causeOther   = "other"
+)
+
+// errorCause classifies err for the summary of failed hosts, so that hosts
+// with the same problem are grouped.
+func errorCause(err error) string {
+	var netErr net.Error
+	var tlsAlert tls.AlertError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var authErr x509.UnknownAuthorityError
+	var syntaxErr *xml.SyntaxError
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return causeRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return causeReset
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return causeTimeout
+	case errors.As(err, &tlsAlert), errors.As(err, &recordErr), errors.As(err, &certErr), errors.As(err, &authErr):
+		return causeTLS
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return causeParse
+	}
+	return causeOther
+}
+
+// failureRecord is a failed host in --output json and yaml.
+type failureRecord struct {
+	IP    string `json:"ip"`
+	Phase string `json:"phase"`
+	Cause string `json:"cause"`
+	Error string `json:"error"`
+}
+
+func failureRecords(errs []ILOError) []failureRecord {
+	res := make([]failureRecord, 0, len(errs))
+	for _, e := range errs {
+		res = append(res, failureRecord{IP: e.IP, Phase: e.Phase, Cause: errorCause(e.Err), Error: e.Err.Error()})
+	}
+	return res
+}
+
+// hostError sends the failure to errs. It is only logged with --verbose,
+// so that it does not break the progress bar.
+func hostError(errs chan<- ILOError, host, phase string, err error) {
+	e := ILOError{IP: host, Phase: phase, Err: err}
+	logger.hostf(levelInfo, logFields{Host: host, Stage: phase}, "%v", e)
+	errs <- e
+}
+
+// errorsRender prints the failed hosts grouped by phase with the cause of
+// each failure, and a count per phase.
+func errorsRender(w io.Writer, errs []ILOError) {
+	if len(errs) == 0 {
+		return
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Phase != errs[j].Phase {
+			return errs[i].Phase < errs[j].Phase
+		}
+		k1, k2 := scanner.IPKey(errs[i].IP), scanner.IPKey(errs[j].IP)
+		return bytes.Compare(k1[:], k2[:]) < 0
+	})
+	counts := map[string]int{}
+	phases := []string{}
+	data := [][]string{}
+	for _, e := range errs {
+		if counts[e.Phase] == 0 {
+			phases = append(phases, e.Phase)
+		}
+		counts[e.Phase]++
+		data = append(data, []string{e.IP, e.Phase, errorCause(e.Err), e.Err.Error()})
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"IP", "Phase", "Cause", "Error"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	table.AppendBulk(data)
+	table.Render()
+	summary := []string{}
+	for _, p := range phases {
+		summary = append(summary, fmt.Sprintf("%s: %d", p, counts[p]))
+	}
+	fmt.Fprintln(w, strings.Join(summary, "  "))
+}
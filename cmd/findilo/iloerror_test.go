@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrorsRenderSortsByAddress(t *testing.T) {
+	errs := []ILOError{
+		{IP: "10.0.0.10", Phase: phaseParse, Err: errors.New("EOF")},
+		{IP: "10.0.0.9", Phase: phaseParse, Err: errors.New("EOF")},
+		{IP: "10.0.0.100", Phase: phaseParse, Err: errors.New("EOF")},
+	}
+	var buf bytes.Buffer
+	errorsRender(&buf, errs)
+	out := buf.String()
+	i9, i10, i100 := strings.Index(out, "10.0.0.9 "), strings.Index(out, "10.0.0.10 "), strings.Index(out, "10.0.0.100 ")
+	if i9 < 0 || i10 < 0 || i100 < 0 || !(i9 < i10 && i10 < i100) {
+		t.Errorf("hosts are not sorted by address:\n%s", out)
+	}
+}
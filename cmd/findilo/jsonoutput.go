@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// ScanReport is the document written by --output json and yaml.
+type ScanReport struct {
+	ScannedAt time.Time   `json:"scanned_at"`
+	Summary   scanSummary `json:"summary"`
+	Hosts     []ILOInfo   `json:"hosts"`
+	// Failures are the hosts that answered the probe but could not be read.
+	Failures []failureRecord `json:"failures,omitempty"`
+}
+
+// scanSummary counts the hosts of a ScanReport. ByGeneration is keyed by
+// the labels of the summary line, like "iLO 4" and "Unknown".
+type scanSummary struct {
+	Total          int            `json:"total"`
+	XMLParseFailed int            `json:"xml_parse_failed"`
+	ByGeneration   map[string]int `json:"by_generation"`
+}
+
+func newScanReport(ilo []ILOInfo, failed []ILOError, scanned time.Time) ScanReport {
+	summary := scanSummary{Total: len(ilo), ByGeneration: summarize(ilo)}
+	for _, e := range failed {
+		if e.Phase == phaseParse {
+			summary.XMLParseFailed++
+		}
+	}
+	return ScanReport{ScannedAt: scanned, Summary: summary, Hosts: ilo, Failures: failureRecords(failed)}
+}
+
+func writeJSON(w io.Writer, ilo []ILOInfo, failed []ILOError, scanned time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newScanReport(ilo, failed, scanned))
+}
+
+// streamJSONL returns a scanRound callback that writes every found host
+// that passes the result filters as one JSON line.
+func streamJSONL(w io.Writer) func(ILOInfo) bool {
+	enc := json.NewEncoder(w)
+	return func(info ILOInfo) bool {
+		if len(filterResults([]ILOInfo{info}, config)) == 0 {
+			return false
+		}
+		if err := enc.Encode(info); err != nil {
+			logger.Errorf("jsonl: %v", err)
+		}
+		return false
+	}
+}
+
+// readJSON reads a scan written by --output json. A plain array of hosts
+// is accepted as well.
+func readJSON(path string) (*ScanReport, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &ScanReport{}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(raw, &report.Hosts)
+	} else {
+		err = json.Unmarshal(raw, report)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
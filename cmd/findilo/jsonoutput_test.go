@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var snakeCase = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// checkKeys reports every key of v that is not snake_case, except the keys
+// of summary.by_generation, which are labels.
+func checkKeys(t *testing.T, path string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if path != ".summary.by_generation" && !snakeCase.MatchString(k) {
+				t.Errorf("key %s.%s is not snake_case", path, k)
+			}
+			checkKeys(t, path+"."+k, child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			checkKeys(t, path+"[]", child)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	hosts := []ILOInfo{
+		{IP: "10.0.0.1", HW: "iLO 4", FW: "2.55", Serial: "CZ1", ServerName: "srv01", DeviceType: deviceILO},
+		{
+			IP: "10.0.0.2", HW: "iLO 5", FW: "2.10", DeviceType: deviceILO,
+			CertExpiry:       time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			SSALogicalDrives: []LogicalDrive{{LogicalDriveNumber: 1, Raid: "1", Status: "OK"}},
+			Warnings:         []string{"cert expires soon"},
+		},
+	}
+	failed := []ILOError{{IP: "10.0.0.3", Phase: phaseParse, Err: errParseFixture}}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, hosts, failed, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	checkKeys(t, "", doc)
+
+	first := doc["hosts"].([]interface{})[0].(map[string]interface{})
+	for _, key := range []string{"cert_expiry", "ssa_logical_drives", "warnings", "mac", "port"} {
+		if _, ok := first[key]; ok {
+			t.Errorf("unset %s is written: %v", key, first[key])
+		}
+	}
+	second := doc["hosts"].([]interface{})[1].(map[string]interface{})
+	if second["cert_expiry"] != "2030-01-01T00:00:00Z" {
+		t.Errorf("cert_expiry = %v", second["cert_expiry"])
+	}
+	summary := doc["summary"].(map[string]interface{})
+	if summary["total"] != 2.0 || summary["xml_parse_failed"] != 1.0 {
+		t.Errorf("summary = %v", summary)
+	}
+
+	path := filepath.Join(t.TempDir(), "scan.json")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	report, err := readJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Hosts) != 2 || report.Hosts[0].ServerName != "srv01" || !report.Hosts[1].CertExpiry.Equal(hosts[1].CertExpiry) {
+		t.Errorf("readJSON = %+v", report.Hosts)
+	}
+}
+
+var errParseFixture = errors.New("EOF")
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type logLevel int
+
+const (
+	levelError logLevel = iota
+	levelWarn
+	levelInfo
+	levelDebug
+)
+
+var levelNames = map[logLevel]string{
+	levelError: "ERROR",
+	levelWarn:  "WARN",
+	levelInfo:  "INFO",
+	levelDebug: "DEBUG",
+}
+
+// maxLoggedBody limits how much of a response body is logged.
+const maxLoggedBody = 512
+
+type leveledLogger struct {
+	sync.Mutex
+	level logLevel
+	w     io.Writer
+	// json writes every message as a JSON object, for --log-format json.
+	json bool
+	// overBar is set while the progress bar is drawn on the terminal; the
+	// bar line is cleared before a message, and the bar redraws itself
+	// below it.
+	overBar bool
+}
+
+var logger = &leveledLogger{level: levelWarn, w: os.Stderr}
+
+// logFields are the structured fields of a message about a host. They are
+// only written separately in JSON; the text of the message names them.
+type logFields struct {
+	Host     string
+	Stage    string
+	Duration time.Duration
+}
+
+// jsonLogLine is a message in --log-format json.
+type jsonLogLine struct {
+	Time       string  `json:"time"`
+	Level      string  `json:"level"`
+	Msg        string  `json:"msg"`
+	Host       string  `json:"host,omitempty"`
+	Stage      string  `json:"stage,omitempty"`
+	DurationMs float64 `json:"duration_ms,omitempty"`
+}
+
+func (l *leveledLogger) logf(level logLevel, format string, args ...interface{}) {
+	l.hostf(level, logFields{}, format, args...)
+}
+
+// hostf logs a message with the fields of the host it is about.
+func (l *leveledLogger) hostf(level logLevel, f logFields, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+	now := time.Now()
+	msg := fmt.Sprintf(format, args...)
+	l.Lock()
+	defer l.Unlock()
+	if l.overBar {
+		fmt.Fprint(l.w, "\r\033[K")
+	}
+	if l.json {
+		raw, _ := json.Marshal(jsonLogLine{
+			Time:       now.UTC().Format(time.RFC3339Nano),
+			Level:      strings.ToLower(levelNames[level]),
+			Msg:        msg,
+			Host:       f.Host,
+			Stage:      f.Stage,
+			DurationMs: float64(f.Duration) / float64(time.Millisecond),
+		})
+		fmt.Fprintf(l.w, "%s\n", raw)
+		return
+	}
+	fmt.Fprintf(l.w, "%s %-5s %s\n", now.Format("15:04:05"), levelNames[level], msg)
+}
+
+func (l *leveledLogger) Errorf(format string, args ...interface{}) {
+	l.logf(levelError, format, args...)
+}
+
+func (l *leveledLogger) Warnf(format string, args ...interface{}) {
+	l.logf(levelWarn, format, args...)
+}
+
+func (l *leveledLogger) Infof(format string, args ...interface{}) {
+	l.logf(levelInfo, format, args...)
+}
+
+func (l *leveledLogger) Debugf(format string, args ...interface{}) {
+	l.logf(levelDebug, format, args...)
+}
+
+func (l *leveledLogger) setOverBar(on bool) {
+	l.Lock()
+	l.overBar = on && isTerminal(os.Stderr)
+	l.Unlock()
+}
+
+// logResponse logs the URL, status code and the beginning of a response body.
+func logResponse(resp *http.Response, body []byte) {
+	if len(body) > maxLoggedBody {
+		body = body[:maxLoggedBody]
+	}
+	logger.hostf(levelInfo, logFields{Host: resp.Request.URL.Hostname(), Stage: "http"},
+		"%s %s: %d %q", resp.Request.Method, resp.Request.URL, resp.StatusCode, body)
+}
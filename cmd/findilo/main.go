@@ -0,0 +1,936 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cheggaaa/pb"
+	"github.com/hdhog/findilo/pkg/fingerprint"
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+	"github.com/hdhog/findilo/pkg/scanner"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	iloPort      = 17988
+	notAvailable = hpilo.NotAvailable
+)
+
+var (
+	ipNetParsed scanner.Targets
+	dialLimiter *rateLimiter
+	progress    *scanProgress
+
+	scanCmd       = kingpin.Command("scan", "Scan networks for iLO interfaces, the default command.").Default()
+	reportCmd     = kingpin.Command("report", "Print the last recorded state of every host.")
+	diffCmd       = kingpin.Command("diff", "Compare two scans written with --output json.")
+	historyCmd    = kingpin.Command("history", "Print the recorded scan history of an IP.")
+	exportCmd     = kingpin.Command("export", "Convert a scan written with --output json to another format.")
+	serveCmd      = kingpin.Command("serve", "Rescan networks at an interval and serve the results over HTTP.")
+	completionCmd = kingpin.Command("completion", "Write a shell completion script to stdout.")
+	versionCmd    = kingpin.Command("version", "Print the version and build details.")
+
+	username       = kingpin.Flag("username", "iLO user for Redfish requests.").Envar("FINDILO_USERNAME").String()
+	password       = kingpin.Flag("password", "iLO password for Redfish requests.").Envar("FINDILO_PASSWORD").String()
+	collectNICTeam = kingpin.Flag("collect-nic-team", "Collect NIC teaming configuration via Redfish.").Bool()
+	requireNICTeam = kingpin.Flag("require-nic-team", "Flag hosts without NIC teaming configured.").Bool()
+	dbPath         = kingpin.Flag("db", "Scan history file, in JSON.").Default(defaultHistoryFile).String()
+	fwDiff         = kingpin.Flag("diff", "Print only hosts whose firmware changed since the last scan.").Bool()
+	historyIP      = kingpin.Flag("history", "Print recorded scan history for an IP and exit.").PlaceHolder("IP").String()
+	output         = kingpin.Flag("output", "Output format.").Short('o').Default("table").Envar("FINDILO_OUTPUT").Action(markOutputSet).Enum("table", "graphite", "json", "jsonl", "csv", "yaml", "markdown")
+	htmlFile       = kingpin.Flag("report-html", "Also write a standalone HTML report to this file.").PlaceHolder("FILE").String()
+	csvFile        = kingpin.Flag("csv", "Also write the table to this CSV file.").PlaceHolder("FILE").String()
+	exportXLSX     = kingpin.Flag("export-xlsx", "Also write the hosts to this Excel file, with sheets per iLO generation and model.").PlaceHolder("FILE").String()
+	outdatedFW     = kingpin.Flag("outdated-fw", "Firmware version to highlight in --export-xlsx (repeatable).").PlaceHolder("VERSION").Strings()
+	graphiteHost   = kingpin.Flag("graphite-host", "Carbon server for --output graphite.").Default("localhost:2003").String()
+	watchInterval  = kingpin.Flag("watch", "Rescan at this interval and print only changes.").PlaceHolder("DURATION").Duration()
+	metricsAddr    = kingpin.Flag("metrics-addr", "Serve Prometheus metrics of the last scan on this address.").PlaceHolder(":9125").String()
+	findSerial     = kingpin.Flag("find-serial", "Stop at the host with this serial number and print only it.").PlaceHolder("SN").String()
+	iloNameFilter  = kingpin.Flag("iloname-filter", "Show only iLOs whose name matches this glob pattern.").PlaceHolder("PATTERN").String()
+	serverFilter   = kingpin.Flag("servername-filter", "Show only hosts whose server name matches this glob pattern.").PlaceHolder("PATTERN").String()
+	cacheFile      = kingpin.Flag("cache-file", "Cache of previous scan results.").Default("~/.findilo.cache").String()
+	skipUnchanged  = kingpin.Flag("skip-unchanged", "Skip the XML parse and the name requests of hosts whose XML did not change.").Bool()
+	noProgress     = kingpin.Flag("no-progress", "Do not show the progress bar.").Bool()
+	withSwitches   = kingpin.Flag("include-switches", "Also detect HPE ProCurve/Aruba switches on port 443.").Bool()
+	sortKey        = kingpin.Flag("sort", "Sort the table by iLO generation or IP.").Default("generation").Enum("generation", "ip")
+	collectLED     = kingpin.Flag("collect-led", "Collect the indicator LED state via Redfish.").Bool()
+	ledBlinking    = kingpin.Flag("filter-led-blinking", "Show only hosts with a blinking indicator LED.").Bool()
+	collectPower   = kingpin.Flag("collect-power", "Collect the server power state via Redfish.").Bool()
+	randomize      = kingpin.Flag("randomize", "Scan the addresses in random order to spread the load across subnets.").Bool()
+	maxDuration    = kingpin.Flag("max-duration", "Stop the scan after this long and report the hosts found so far.").PlaceHolder("DURATION").Duration()
+	maxRespTime    = kingpin.Flag("max-response-time", "Drop hosts whose XML response takes longer.").PlaceHolder("DURATION").Duration()
+	showLatency    = kingpin.Flag("show-latency", "Show the XML response time column.").Bool()
+	verbose        = kingpin.Flag("verbose", "Log every HTTP request with its status and response body.").Bool()
+	logFormat      = kingpin.Flag("log-format", "Format of the log messages on stderr.").Default("text").Enum("text", "json")
+	debug          = kingpin.Flag("debug", "Like --verbose, also log every port probe and the parsed responses.").Bool()
+	workers        = kingpin.Flag("concurrency", "Number of concurrent scan workers.").Default("100").Envar("FINDILO_CONCURRENCY").Int()
+	rate           = kingpin.Flag("rate", "Maximum new connections per second across all workers, 0 is unlimited.").Default("0").Int()
+	httpsBanner    = kingpin.Flag("check-https-banner", "Identify iLOs on port 443 when the --port is closed.").Bool()
+	httpTimeout    = kingpin.Flag("http-timeout", "Timeout of a single HTTP request.").Default("5s").Envar("FINDILO_TIMEOUT").Duration()
+	probePort      = kingpin.Flag("port", "TCP port probed to detect an iLO and to read its XML from.").Default(strconv.Itoa(iloPort)).Int()
+	multiPort      = kingpin.Flag("multi-port", "Also probe 17988, 443, 80 and IPMI on 623/udp when the --port is closed.").Bool()
+	altPort        = kingpin.Flag("alt-port", "HTTPS port to read the XML from when the --port is closed, 0 disables.").Default("443").Int()
+	connTimeout    = kingpin.Flag("connect-timeout", "Timeout of the TCP port probe, raise it for WAN links.").Default("250ms").Duration()
+	retries        = kingpin.Flag("retries", "Retry a failed probe or HTTP request this many times, with doubling pauses.").Default("0").Int()
+	awsRegion      = kingpin.Flag("aws-region", "Match discovered IPs against EC2 instances of this region.").String()
+	certCheck      = kingpin.Flag("cert-check", "Read the expiry date of the HTTPS certificate.").Bool()
+	sessionCheck   = kingpin.Flag("check-session-timeout", "Read the session timeout via Redfish.").Bool()
+	sessionMax     = kingpin.Flag("require-session-timeout-max", "Flag hosts with a longer session timeout.").PlaceHolder("MINUTES").Int()
+	sessionSet     = kingpin.Flag("set-session-timeout", "Set the session timeout via Redfish.").PlaceHolder("MINUTES").Int()
+	collectSSA     = kingpin.Flag("collect-ssa", "Collect Smart Array logical drives via Redfish.").Bool()
+	raidAlert      = kingpin.Flag("alert-raid-degraded", "Alert on degraded or failed logical drives.").Bool()
+	otelEndpoint   = kingpin.Flag("otel-endpoint", "Send the scan trace and metrics to this OTLP collector, over gRPC for grpc:// and grpcs://, over HTTP for http:// and https://.").PlaceHolder("grpc://otelcol:4317").String()
+	otelService    = kingpin.Flag("otel-service-name", "service.name resource attribute of OTLP data.").Default("findilo").String()
+	verifyDNS      = kingpin.Flag("verify-dns", "Check that forward and reverse DNS of the iLO name agree.").Bool()
+	skipDNSBad     = kingpin.Flag("exclude-dns-mismatch", "Drop hosts whose forward and reverse DNS disagree.").Bool()
+	discoverAPI    = kingpin.Flag("discover-api-version", "Read the Redfish version and skip Redfish checks on hosts without it.").Bool()
+	amplifierURL   = kingpin.Flag("amplifier-url", "Read hosts from this iLO Amplifier Pack instead of scanning.").PlaceHolder("https://amplifier").String()
+	amplifierToken = kingpin.Flag("amplifier-token", "X-Auth-Token of an iLO Amplifier Pack session.").Envar("FINDILO_AMPLIFIER_TOKEN").String()
+	amplifierQuery = kingpin.Flag("amplifier-filter", "Filter Amplifier Pack hosts, model=VALUE or fw=VALUE (repeatable).").Strings()
+	federation     = kingpin.Flag("federation", "Also scan the iLO federation peers of discovered iLOs.").Bool()
+	fedDepth       = kingpin.Flag("federation-depth", "How many hops of federation peers to follow.").Default("1").Int()
+	defaultCreds   = kingpin.Flag("check-default-creds", "Try factory default logins, at most two per host and once per run, also with --watch and serve.").Bool()
+	proxy          = kingpin.Flag("proxy", "HTTP proxy for all requests, overrides HTTP_PROXY and HTTPS_PROXY.").PlaceHolder("URL").String()
+	hostsOnly      = kingpin.Flag("hosts-only", "Print only the IPs of discovered iLOs, one per line.").Bool()
+	arp            = kingpin.Flag("arp", "Probe only hosts on directly attached networks that answer ARP, and show their MAC.").Bool()
+	ping           = kingpin.Flag("ping", "Probe only hosts that answer an ICMP echo, skipping dead addresses.").Bool()
+	autoNetworks   = kingpin.Flag("auto", "Scan the private networks of the local interfaces.").Bool()
+	autoMinPrefix  = kingpin.Flag("auto-min-prefix", "Skip --auto networks with a shorter prefix than this.").Default("16").Int()
+	configFile     = kingpin.Flag("config", "YAML file with flag defaults.").Default(defaultConfigFile).String()
+	_              = kingpin.Flag("profile", "Use the flag defaults of this profile of the --config file.").HintAction(profileNames).String()
+	initConfig     = kingpin.Flag("init", "Write an example config file and exit.").Bool()
+	quiet          = kingpin.Flag("quiet", "Print only the results, without progress and the summary of failed hosts.").Bool()
+	testFlags      = kingpin.Flag("test-flag-combinations", "Only validate the flag combinations and exit.").Bool()
+	_              = kingpin.Flag("generate-manpage", "Write a man page to stdout and exit.").PreAction(generateManPage).Bool()
+)
+
+// ILOInfo ...
+type ILOInfo struct {
+	IP         string `json:"ip"`
+	HW         string `json:"hw"`
+	Model      string `json:"model"`
+	FW         string `json:"fw"`
+	Serial     string `json:"serial"`
+	ServerName string `json:"server_name"`
+	IloName    string `json:"ilo_name"`
+	DeviceType string `json:"device_type"`
+
+	NICTeamEnabled bool      `json:"nic_team_enabled,omitempty"`
+	NICTeamMode    string    `json:"nic_team_mode,omitempty"`
+	LEDState       string    `json:"led_state,omitempty"`
+	ResponseTimeMs int64     `json:"response_time_ms,omitempty"`
+	ILOOnHTTPS     bool      `json:"ilo_on_https,omitempty"`
+	Port           int       `json:"port,omitempty"`
+	MAC            string    `json:"mac,omitempty"`
+	EC2InstanceID  string    `json:"ec2_instance_id,omitempty"`
+	EC2Region      string    `json:"ec2_region,omitempty"`
+	CertExpiry     time.Time `json:"cert_expiry,omitzero"`
+
+	SessionTimeoutMinutes int            `json:"session_timeout_minutes,omitempty"`
+	SSALogicalDrives      []LogicalDrive `json:"ssa_logical_drives,omitempty"`
+	FQDN                  string         `json:"fqdn,omitempty"`
+	ForwardDNSMatch       bool           `json:"forward_dns_match,omitempty"`
+	DNSMismatch           bool           `json:"dns_mismatch,omitempty"`
+	APIVersion            string         `json:"api_version,omitempty"`
+	RedfishProduct        string         `json:"redfish_product,omitempty"`
+	FederationPeers       []string       `json:"federation_peers,omitempty"`
+	DefaultCreds          bool           `json:"default_creds,omitempty"`
+	PowerState            string         `json:"power_state,omitempty"`
+
+	sessionsPath string
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ILOSorter ...
+type ILOSorter struct {
+	ilo []ILOInfo
+	by  func(i1, i2 *ILOInfo) bool
+}
+
+// By is the type of a "less" function that defines the ordering of its Planet arguments.
+type By func(i1, i2 *ILOInfo) bool
+
+// Sort is a method on the function type, By, that sorts the argument slice according to the function.
+func (by By) Sort(ilo []ILOInfo) {
+	ps := &ILOSorter{
+		ilo: ilo,
+		by:  by, // The Sort method's receiver is the function (closure) that defines the sort order.
+	}
+	sort.Sort(ps)
+}
+
+// Len is part of sort.Interface.
+func (s *ILOSorter) Len() int {
+	return len(s.ilo)
+}
+
+// Swap is part of sort.Interface.
+func (s *ILOSorter) Swap(i, j int) {
+	s.ilo[i], s.ilo[j] = s.ilo[j], s.ilo[i]
+}
+
+// Less is part of sort.Interface. It is implemented by calling the "by" closure in the sorter.
+func (s *ILOSorter) Less(i, j int) bool {
+	return s.by(&s.ilo[i], &s.ilo[j])
+}
+
+// IsOpen probes the TCP port within the connect timeout, which is kept
+// short so dead addresses are rejected quickly.
+func IsOpen(ctx context.Context, cfg *Config, host string, port int) bool {
+	if dialLimiter.waitContext(ctx) != nil {
+		return false
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.ConnTimeout}
+	start := time.Now()
+	err := retry(ctx, cfg, func() error {
+		return scanner.Probe(ctx, dialer, host, port)
+	})
+	fields := logFields{Host: host, Stage: "probe", Duration: time.Since(start)}
+	if err != nil {
+		logger.hostf(levelDebug, fields, "probe: %v", err)
+		return false
+	}
+	logger.hostf(levelDebug, fields, "probe: %s open", net.JoinHostPort(host, strconv.Itoa(port)))
+	return true
+}
+
+// newHTTPClient builds the client of the services that are not iLOs, like
+// the AWS API, with the proxy and timeout settings of cfg. Certificates are
+// verified and the --custom-header headers, which are meant for proxies in
+// front of the iLOs, are not sent. Without --proxy, HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY are honored.
+func newHTTPClient(cfg Config) *http.Client {
+	cfg.InsecureTLS = false
+	return &http.Client{Transport: newTransport(cfg), Timeout: cfg.HTTPTimeout}
+}
+
+func newTransport(cfg Config) *http.Transport {
+	proxyFunc := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		if u, err := url.Parse(cfg.Proxy); err == nil {
+			proxyFunc = http.ProxyURL(u)
+		}
+	}
+	return &http.Transport{
+		Proxy:             proxyFunc,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: cfg.InsecureTLS},
+		DisableKeepAlives: true,
+	}
+}
+
+// iloClient returns the client for the iLO requests of cfg. iLO
+// certificates are self-signed, so they are not verified. Its requests
+// wait for dialLimiter before the --http-timeout starts, so a low --rate
+// does not time them out.
+func iloClient(cfg *Config) *http.Client {
+	c := *cfg
+	c.InsecureTLS = true
+	tr := &limitTransport{base: newTransport(c), timeout: c.HTTPTimeout}
+	return &http.Client{Transport: &headerTransport{base: tr}}
+}
+
+// getContext is client.Get bound to ctx.
+func getContext(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// iloDoer sends the requests of package ilo with the retries of cfg and
+// logs every response.
+type iloDoer struct {
+	cfg    *Config
+	client *http.Client
+}
+
+// newILODoer returns the doer of the iLO requests of cfg. It does not
+// follow redirects, so that ilo.FetchXML sees the HTTPS redirect.
+func newILODoer(cfg *Config) iloDoer {
+	client := iloClient(cfg)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return iloDoer{cfg: cfg, client: client}
+}
+
+func (d iloDoer) Do(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := retry(req.Context(), d.cfg, func() error {
+		var err error
+		resp, err = d.client.Do(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	logResponse(resp, body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// discoverPhases are the scan phases of the steps of hpilo.Discover.
+var discoverPhases = map[string]string{
+	hpilo.StepXML:   phaseXML,
+	hpilo.StepParse: phaseParse,
+	hpilo.StepNames: phaseServerName,
+}
+
+// newILOInfo returns the host of what hpilo.Discover read.
+func newILOInfo(found *hpilo.Info) *ILOInfo {
+	info := &ILOInfo{
+		IP:             found.IP,
+		HW:             found.HW,
+		FW:             found.FW,
+		Model:          found.Model,
+		Serial:         found.Serial,
+		ServerName:     found.ServerName,
+		IloName:        found.ILOName,
+		DeviceType:     deviceILO,
+		ResponseTimeMs: int64(found.ResponseTime / time.Millisecond),
+	}
+	if info.HW == notAvailable {
+		info.DeviceType = deviceUnknown
+	}
+	return info
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// tableRows sorts ilo and returns the header and rows of the table, with
+// the optional columns of the given flags.
+func tableRows(ilo []ILOInfo) ([]string, [][]string) {
+	data := [][]string{}
+	header := []string{"IP", "HW", "FW", "S/N", "Model", "ServerName", "Name"}
+	if *withSwitches {
+		header = append(header, "Type")
+	}
+	if *arp {
+		header = append(header, "MAC")
+	}
+	if *collectNICTeam || *requireNICTeam {
+		header = append(header, "NIC team")
+	}
+	if *collectLED || *ledBlinking {
+		header = append(header, "LED")
+	}
+	if *showLatency {
+		header = append(header, "Resp(ms)")
+	}
+	if *httpsBanner {
+		header = append(header, "HTTPS only")
+	}
+	if *awsRegion != "" {
+		header = append(header, "EC2 instance")
+	}
+	if *certCheck {
+		header = append(header, "Cert expiry")
+	}
+	if *sessionCheck || *sessionMax > 0 || *sessionSet > 0 {
+		header = append(header, "Session(min)")
+	}
+	if *collectSSA || *raidAlert {
+		header = append(header, "Logical drives")
+	}
+	if *verifyDNS || *skipDNSBad {
+		header = append(header, "FQDN")
+	}
+	if *discoverAPI {
+		header = append(header, "Redfish")
+	}
+	if *defaultCreds {
+		header = append(header, "Default creds")
+	}
+	if *collectPower {
+		header = append(header, "Power")
+	}
+	withWarnings := false
+	for _, info := range ilo {
+		if len(info.Warnings) > 0 {
+			withWarnings = true
+			header = append(header, "Warnings")
+			break
+		}
+	}
+	byIP := func(i1, i2 *ILOInfo) bool {
+		k1, k2 := scanner.IPKey(i1.IP), scanner.IPKey(i2.IP)
+		return bytes.Compare(k1[:], k2[:]) < 0
+	}
+	version := func(i1, i2 *ILOInfo) bool {
+		g1, g2 := hpilo.Generation(i1.HW), hpilo.Generation(i2.HW)
+		if g1 != g2 {
+			return g1 < g2
+		}
+		return byIP(i1, i2)
+	}
+	if *sortKey == "ip" {
+		By(byIP).Sort(ilo)
+	} else {
+		By(version).Sort(ilo)
+	}
+	for _, info := range ilo {
+		row := []string{
+			info.IP,
+			info.HW,
+			info.FW,
+			info.Serial,
+			info.Model,
+			info.ServerName,
+			info.IloName,
+		}
+		if *withSwitches {
+			row = append(row, info.DeviceType)
+		}
+		if *arp {
+			row = append(row, orNotAvailable(info.MAC))
+		}
+		if *collectNICTeam || *requireNICTeam {
+			row = append(row, info.nicTeam())
+		}
+		if *collectLED || *ledBlinking {
+			row = append(row, info.LEDState)
+		}
+		if *showLatency {
+			row = append(row, strconv.FormatInt(info.ResponseTimeMs, 10))
+		}
+		if *httpsBanner {
+			row = append(row, yesNo(info.ILOOnHTTPS))
+		}
+		if *awsRegion != "" {
+			row = append(row, info.EC2InstanceID)
+		}
+		if *certCheck {
+			row = append(row, certExpiryString(info))
+		}
+		if *sessionCheck || *sessionMax > 0 || *sessionSet > 0 {
+			row = append(row, strconv.Itoa(info.SessionTimeoutMinutes))
+		}
+		if *collectSSA || *raidAlert {
+			row = append(row, logicalDrivesString(info))
+		}
+		if *verifyDNS || *skipDNSBad {
+			row = append(row, dnsString(info))
+		}
+		if *discoverAPI {
+			row = append(row, strings.TrimSpace(info.APIVersion+" "+info.RedfishProduct))
+		}
+		if *defaultCreds {
+			row = append(row, yesNo(info.DefaultCreds))
+		}
+		if *collectPower {
+			row = append(row, orNotAvailable(info.PowerState))
+		}
+		if withWarnings {
+			row = append(row, strings.Join(info.Warnings, ", "))
+		}
+		data = append(data, row)
+	}
+	return header, data
+}
+
+func tableRender(ilo []ILOInfo) {
+	header, data := tableRows(ilo)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.SetBorder(false) // Set Border to false
+	table.AppendBulk(data) // Add Bulk Data
+	fmt.Println("")
+	table.Render()
+}
+
+// iloFingerprinter finds iLOs by their XML on the --port, the --alt-port
+// and, with --multi-port, the multiPorts.
+type iloFingerprinter struct {
+	cfg *Config
+}
+
+func init() {
+	fingerprint.Register(orderILO, "ilo", iloFingerprinter{cfg: &config})
+}
+
+func (f iloFingerprinter) Ports() []int {
+	cfg := f.cfg
+	ports := []int{cfg.ProbePort}
+	if cfg.AltPort != 0 && cfg.AltPort != cfg.ProbePort {
+		ports = append(ports, cfg.AltPort)
+	}
+	if cfg.MultiPort {
+		for _, port := range multiPorts {
+			if port != cfg.ProbePort && port != cfg.AltPort {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// Identify reads the XML and the names of an iLO from the port it answered
+// on, see requestPort. A host that answers on the --port is taken for an
+// iLO even when it cannot be read. On another port, like the --alt-port, a
+// failed XML request only means there is no iLO.
+func (f iloFingerprinter) Identify(ctx context.Context, host string, port int) (*fingerprint.Device, bool, error) {
+	cfg := f.cfg
+	final := port == cfg.ProbePort
+	reqPort := requestPort(port)
+	var known *hpilo.Info
+	if cfg.SkipUnchanged {
+		known = cache.lookup(host)
+	}
+	found, err := hpilo.Discover(ctx, newILODoer(cfg), host, reqPort, known)
+	var failed *hpilo.Error
+	if err != nil && !errors.As(err, &failed) {
+		failed = &hpilo.Error{Step: hpilo.StepXML, Err: err}
+	}
+	if found == nil {
+		if failed.Step == hpilo.StepXML && !final {
+			logger.Debugf("%s:%d: %v", host, port, failed.Err)
+			return nil, false, nil
+		}
+		return nil, final, ILOError{IP: host, Phase: discoverPhases[failed.Step], Err: failed.Err}
+	}
+	if cfg.MaxRespTime > 0 && found.ResponseTime > cfg.MaxRespTime {
+		return nil, final, nil
+	}
+	if known != nil && found.Checksum == known.Checksum {
+		logger.Debugf("skipped unchanged: %s", host)
+	} else {
+		logger.Debugf("%s: %+v", host, *found)
+	}
+	info := newILOInfo(found)
+	if !final {
+		info.Port = port
+	}
+	info.ILOOnHTTPS = reqPort != 0
+	if err != nil {
+		return info.device(), true, ILOError{IP: host, Phase: discoverPhases[failed.Step], Err: failed.Err}
+	}
+	if cfg.SkipUnchanged {
+		cache.store(host, *found)
+	}
+	return info.device(), true, nil
+}
+
+// Enrich collects the DNS, certificate and Redfish details of the flags.
+// It runs for hosts reused from the --skip-unchanged cache too, since
+// these details can change while the XML stays the same.
+func (f iloFingerprinter) Enrich(ctx context.Context, dev *fingerprint.Device) error {
+	cfg := f.cfg
+	info := dev.Details.(*ILOInfo)
+	host := info.IP
+	var errs []error
+	fail := func(phase string, err error) {
+		errs = append(errs, ILOError{IP: host, Phase: phase, Err: err})
+	}
+	if cfg.VerifyDNS || cfg.SkipDNSBad {
+		if err := checkDNS(info); err != nil {
+			fail(phaseDNS, err)
+		}
+	}
+	redfish := true
+	if cfg.DiscoverAPI {
+		if err := requestServiceRoot(ctx, cfg, host, info); err != nil {
+			fail(phaseRedfish, err)
+			logger.Debugf("%s: no Redfish service, skipping Redfish checks", host)
+			redfish = false
+		}
+	}
+	if redfish && cfg.DefaultCreds {
+		if err := checkDefaultCreds(ctx, cfg, host, info); err != nil {
+			fail(phaseRedfish, err)
+		}
+	}
+	if redfish && cfg.Federation {
+		if err := collectFederationPeers(ctx, cfg, host, info); err != nil {
+			fail(phaseRedfish, err)
+		}
+	}
+	if redfish && (cfg.CollectNIC || cfg.RequireNIC) {
+		if err := requestNICTeam(ctx, cfg, host, info); err != nil {
+			fail(phaseRedfish, err)
+		}
+		if cfg.RequireNIC && !info.NICTeamEnabled {
+			info.Warnings = append(info.Warnings, "no NIC teaming")
+		}
+	}
+	if redfish && (cfg.CollectLED || cfg.LEDBlinking || cfg.CollectPower) {
+		if err := requestSystem(ctx, cfg, host, info); err != nil {
+			fail(phaseRedfish, err)
+		}
+	}
+	if cfg.CertCheck {
+		if err := checkCert(ctx, cfg, host, info, time.Now()); err != nil {
+			fail(phaseCert, err)
+		}
+	}
+	if redfish && (cfg.SessionCheck || cfg.SessionMax > 0 || cfg.SessionSet > 0) {
+		if err := requestSessionTimeout(ctx, cfg, host, info, cfg.SessionSet); err != nil {
+			fail(phaseRedfish, err)
+		} else if cfg.SessionMax > 0 && info.SessionTimeoutMinutes > cfg.SessionMax {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("session timeout %d min", info.SessionTimeoutMinutes))
+		}
+	}
+	if redfish && (cfg.CollectSSA || cfg.RaidAlert) {
+		if err := requestLogicalDrives(ctx, cfg, host, info); err != nil {
+			fail(phaseRedfish, err)
+		}
+		if cfg.RaidAlert {
+			alertDegradedDrives(info)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requestPort returns the port to send the iLO requests to for a host that
+// answered on port. The iLO port and HTTP use the standard requests, any
+// other port is requested over HTTPS.
+func requestPort(port int) int {
+	if port == iloPort || port == httpPort {
+		return 0
+	}
+	return port
+}
+
+// hostScanner scans the hosts of one round. A host with an open port of
+// a fingerprinter is identified in the identify group while the next
+// hosts are probed. The group is limited to one host per worker, so the
+// round holds no more than filesPerWorker connections per worker. Its
+// goroutines report failures to errs and never return an error: one
+// failed host must not cancel the others.
+type hostScanner struct {
+	cfg      *Config
+	out      chan<- ILOInfo
+	errs     chan<- ILOError
+	bar      *pb.ProgressBar
+	counters *scanCounters
+	identify *errgroup.Group
+}
+
+// done counts a host as scanned. Hosts cut short by the end of ctx are
+// left to a resumed scan.
+func (s *hostScanner) done(ctx context.Context, host string, found bool) {
+	if found || ctx.Err() == nil {
+		progress.markScanned(host)
+	}
+	atomic.AddInt64(&s.counters.scanned, 1)
+	if s.bar != nil {
+		s.bar.Increment()
+	}
+}
+
+// scan probes host from a scanner.Run worker and identifies it in the
+// background.
+func (s *hostScanner) scan(ctx context.Context, host string) {
+	if ctx.Err() != nil {
+		return
+	}
+	start := time.Now()
+	probe := fingerprint.Default.NewHost(host, func(ctx context.Context, port int) bool {
+		return IsOpen(ctx, s.cfg, host, port)
+	})
+	if !probe.Probe(ctx) {
+		s.done(ctx, host, false)
+		return
+	}
+	s.identify.Go(func() error {
+		info := identifyHost(ctx, probe, host, s.errs)
+		if info != nil {
+			info.MAC = arpMACs[host]
+			atomic.AddInt64(&s.counters.found, 1)
+			s.out <- *info
+		}
+		tracer.record(host, start, info != nil)
+		s.done(ctx, host, info != nil)
+		return nil
+	})
+}
+
+// runScan scans ips and, with --federation, the federation peers found on
+// the way. The results are then filtered, correlated and published. When
+// ctx is done the hosts found until then are returned.
+func runScan(ctx context.Context, ips scanner.Targets, showBar bool) ([]ILOInfo, []ILOError) {
+	start := time.Now()
+	var onFound func(ILOInfo) bool
+	if *output == "jsonl" {
+		onFound = streamJSONL(os.Stdout)
+	}
+	// The hosts are probed until the --max-duration; the EC2 requests
+	// after the scan are only canceled by an interrupt.
+	scanCtx, cancel := withMaxDuration(ctx)
+	defer cancel()
+	record := onFound
+	stopSave := func() {}
+	if progress != nil {
+		stopSave = progress.autosave()
+		record = func(info ILOInfo) bool {
+			progress.addHost(info)
+			return onFound != nil && onFound(info)
+		}
+	}
+	ilo, failed := scanRound(scanCtx, ips, showBar, record)
+	stopSave()
+	if progress != nil {
+		ilo = append(progress.prevHosts, ilo...)
+		progress.finish(scanCtx.Err() != nil)
+	}
+	if errors.Is(scanCtx.Err(), context.DeadlineExceeded) {
+		logger.Warnf("--max-duration reached, reporting the %d hosts found so far", len(ilo))
+	} else if scanCtx.Err() != nil {
+		logger.Warnf("interrupted, reporting the %d hosts found so far", len(ilo))
+	} else if *federation {
+		seen := map[string]bool{}
+		found := ilo
+		for depth := 0; depth < *fedDepth; depth++ {
+			peers := unscannedPeers(found, ips, seen)
+			if len(peers) == 0 || scanCtx.Err() != nil {
+				break
+			}
+			logger.Infof("federation: scanning %d new peers", len(peers))
+			var more []ILOError
+			found, more = scanRound(scanCtx, scanner.TargetsOf(peers), false, onFound)
+			ilo = append(ilo, found...)
+			failed = append(failed, more...)
+		}
+	}
+	ilo = filterResults(ilo, config)
+	warnDuplicateSerials(ilo)
+	if *awsRegion != "" {
+		if err := correlateEC2(ctx, ilo, *awsRegion); err != nil {
+			logger.Errorf("ec2: %v", err)
+		}
+	}
+	metrics.update(ilo, failed, time.Since(start))
+	if *otelEndpoint != "" {
+		if err := otelExport(*otelEndpoint, *otelService, ilo, start, time.Now()); err != nil {
+			logger.Errorf("otel: %v", err)
+		}
+	}
+	if *skipUnchanged {
+		if err := cache.save(*cacheFile); err != nil {
+			logger.Errorf("cache: %v", err)
+		}
+	}
+	return ilo, failed
+}
+
+// withMaxDuration limits ctx to the --max-duration, if one is set.
+func withMaxDuration(ctx context.Context) (context.Context, context.CancelFunc) {
+	if config.MaxDuration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, config.MaxDuration)
+}
+
+// scanRound probes ips with the scan workers. A non-nil onFound is called
+// for every found host as it arrives; when it returns true the remaining
+// hosts are not probed.
+func scanRound(ctx context.Context, ips scanner.Targets, showBar bool, onFound func(ILOInfo) bool) ([]ILOInfo, []ILOError) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	out := make(chan ILOInfo, 100)
+	errs := make(chan ILOError, 100)
+	total := ips.Count()
+
+	var scanbar *pb.ProgressBar
+	counters := &scanCounters{}
+	stopBar := make(chan struct{})
+	barDone := make(chan struct{})
+	showBar = showBar && !*quiet
+	if showBar && !*noProgress && canDrawBar() {
+		scanbar = pb.New(total)
+		scanbar = scanbar.Prefix("Scan net")
+		scanbar.ShowTimeLeft = false
+		scanbar.ManualUpdate = true
+		scanbar.Start()
+		logger.setOverBar(true)
+		go updateBar(scanbar, counters, stopBar, barDone)
+	} else if showBar {
+		stop := make(chan struct{})
+		defer close(stop)
+		go logProgress(counters, total, stop)
+	}
+
+	//Запуск воркеров
+	count := workerCount(config.Workers)
+	hs := &hostScanner{
+		cfg:      &config,
+		out:      out,
+		errs:     errs,
+		bar:      scanbar,
+		counters: counters,
+		identify: new(errgroup.Group),
+	}
+	hs.identify.SetLimit(count)
+	go func() {
+		scanner.Run(ctx, ips, count, *randomize, hs.scan)
+		hs.identify.Wait()
+		close(out)
+		close(errs)
+	}()
+
+	failed := []ILOError{}
+	errsDone := make(chan struct{})
+	go func() {
+		for e := range errs {
+			failed = append(failed, e)
+		}
+		close(errsDone)
+	}()
+	ilo := []ILOInfo{}
+	for info := range out {
+		ilo = append(ilo, info)
+		if onFound != nil && onFound(info) {
+			cancel()
+		}
+	}
+	<-errsDone
+	if scanbar != nil {
+		close(stopBar)
+		<-barDone
+		scanbar.Finish()
+		logger.setOverBar(false)
+	}
+	return ilo, failed
+}
+
+func report(ilo []ILOInfo, failed []ILOError) {
+	now := time.Now()
+	changes := recordHistory(*dbPath, ilo, now)
+	if *hostsOnly {
+		hostsRender(os.Stdout, ilo)
+		return
+	}
+	switch {
+	case *output == "graphite":
+		if err := graphiteSend(*graphiteHost, ilo, now); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	case *output == "json":
+		if err := writeJSON(os.Stdout, ilo, failed, now.UTC()); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	case *output == "jsonl":
+		// The hosts were written as they were found.
+	case *output == "yaml":
+		if err := writeYAML(os.Stdout, newScanReport(ilo, failed, now.UTC())); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	case *output == "markdown":
+		if err := writeMarkdown(os.Stdout, ilo); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	case *output == "csv":
+		if err := writeCSV(os.Stdout, ilo); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	case *fwDiff:
+		fwChangesRender(changes)
+		fmt.Println("")
+	default:
+		tableRender(ilo)
+		fmt.Println(summaryLine(ilo, failed))
+		fmt.Println("")
+	}
+	if *htmlFile != "" {
+		if err := writeHTMLFile(*htmlFile, ilo, failed, *networks, metrics.lastDuration(), now); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if *csvFile != "" {
+		if err := writeCSVFile(*csvFile, ilo); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if *exportXLSX != "" {
+		if err := writeXLSX(*exportXLSX, xlsxTables(ilo), *outdatedFW); err != nil {
+			logger.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	if !*quiet {
+		errorsRender(os.Stderr, failed)
+	}
+}
+
+func main() {
+	kingpin.CommandLine.Help = "Find HP iLO management interfaces in networks."
+	kingpin.Version(versionString())
+	os.Args = rewriteArgs(os.Args)
+	if path, explicit := configFileArg(os.Args[1:]); !hasArg(os.Args[1:], "--init") {
+		profile, _ := flagArg(os.Args[1:], "--profile")
+		if err := applyConfigFile(kingpin.CommandLine, path, explicit, profile); err != nil {
+			kingpin.Fatalf("%v", err)
+		}
+	}
+	command := kingpin.Parse()
+	if *initConfig {
+		writeExampleConfig(kingpin.CommandLine, *configFile)
+		return
+	}
+	logger.json = *logFormat == "json"
+	switch {
+	case *debug:
+		logger.level = levelDebug
+	case *verbose:
+		logger.level = levelInfo
+	}
+	config = configFromFlags()
+	if *testFlags {
+		testFlagCombinations(config)
+	}
+	if err := validateFlags(config); err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+	switch command {
+	case reportCmd.FullCommand():
+		reportCommand()
+	case diffCmd.FullCommand():
+		diffCommand()
+	case historyCmd.FullCommand():
+		historyCommand()
+	case exportCmd.FullCommand():
+		exportCommand()
+	case serveCmd.FullCommand():
+		serveCommand()
+	case completionCmd.FullCommand():
+		completionCommand()
+	case versionCmd.FullCommand():
+		versionCommand()
+	default:
+		scanCommand()
+	}
+}
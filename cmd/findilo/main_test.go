@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+// iloFixture returns a file of the pkg/ilo testdata.
+func iloFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := ioutil.ReadFile(filepath.Join("..", "..", "pkg", "ilo", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestIdentifyFillsILOInfo(t *testing.T) {
+	for _, tt := range []struct {
+		rimp []byte
+		want ILOInfo
+	}{
+		{iloFixture(t, "ilo2.xml"), ILOInfo{HW: "iLO 2", FW: "2.33", Model: "ProLiant DL380 G5", Serial: "GB8704ABCD",
+			ServerName: "srv-g5", IloName: "ilo-srv-g5", DeviceType: deviceILO}},
+		{iloFixture(t, "ilo3.xml"), ILOInfo{HW: "iLO 3", FW: "1.94", Model: "ProLiant DL360 G7", Serial: "CZJ1230ABC",
+			ServerName: "srv-gen10", IloName: "ilo-srv-gen10", DeviceType: deviceILO}},
+		{iloFixture(t, "ilo4.xml"), ILOInfo{HW: "iLO 4", FW: "2.55", Model: "ProLiant DL380 Gen9", Serial: "CZ1234ABCD",
+			ServerName: "srv-gen10", IloName: "ilo-srv-gen10", DeviceType: deviceILO}},
+		{iloFixture(t, "ilo5.xml"), ILOInfo{HW: "iLO 5", FW: "2.72", Model: "ProLiant DL380 Gen10", Serial: "CZ2D1234AB",
+			ServerName: "srv-gen10", IloName: "ilo-srv-gen10", DeviceType: deviceILO}},
+		{iloFixture(t, "ilo5-empty.xml"), ILOInfo{HW: "iLO 5", FW: "2.72", Model: notAvailable, Serial: "CZ2D1234AB",
+			ServerName: "srv-gen10", IloName: "ilo-srv-gen10", DeviceType: deviceILO}},
+		// Without a generation the names are read from the login page.
+		{[]byte(`<RIMP><HSI><SBSN>X1</SBSN></HSI></RIMP>`), ILOInfo{HW: notAvailable, FW: notAvailable, Model: notAvailable, Serial: "X1",
+			ServerName: "srv-g5", IloName: "ilo-srv-g5", DeviceType: deviceUnknown}},
+	} {
+		page, session := iloFixture(t, "ilo2-login.html"), iloFixture(t, "login_session.json")
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/xmldata":
+				w.Write(tt.rimp)
+			case "/json/login_session":
+				w.Write(session)
+			case "/":
+				w.Write(page)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := validConfig()
+		cfg.ProbePort = port
+		dev, _, err := iloFingerprinter{cfg: &cfg}.Identify(context.Background(), "127.0.0.1", port)
+		srv.Close()
+		if dev == nil || err != nil {
+			t.Errorf("%s: Identify = %+v, %v", tt.want.HW, dev, err)
+			continue
+		}
+		got := *deviceInfo(dev)
+		got.ResponseTimeMs = 0
+		want := tt.want
+		want.IP, want.ILOOnHTTPS = "127.0.0.1", true
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: host = %+v, want %+v", tt.want.HW, got, want)
+		}
+	}
+}
+
+func TestIdentifyHTTPTimeout(t *testing.T) {
+	for _, slowBody := range []bool{false, true} {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slowBody {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+			}
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+		}))
+		_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+		port, _ := strconv.Atoi(portStr)
+
+		cfg := validConfig()
+		cfg.ProbePort = port
+		cfg.HTTPTimeout = 100 * time.Millisecond
+		start := time.Now()
+		_, _, err := iloFingerprinter{cfg: &cfg}.Identify(context.Background(), "127.0.0.1", port)
+		elapsed := time.Since(start)
+		srv.Close()
+		e, ok := err.(ILOError)
+		if !ok || e.Phase != phaseXML || errorCause(e.Err) != causeTimeout {
+			t.Errorf("slow body %v: err = %v, want a %s timeout", slowBody, err, phaseXML)
+		}
+		if elapsed > time.Second {
+			t.Errorf("slow body %v: Identify took %v with --http-timeout 100ms", slowBody, elapsed)
+		}
+	}
+}
+
+// TestILODoerRequests runs the iLO requests through the net/http client
+// of the scan: the self-signed certificate is accepted, the first request
+// is retried after a dropped connection, and every request carries the
+// User-Agent and the --custom-header headers.
+func TestILODoerRequests(t *testing.T) {
+	saved := customHeaders.Header
+	defer func() { customHeaders.Header = saved }()
+	customHeaders.Header = nil
+	if err := customHeaders.Set("X-WAF-Token: secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	rimp, session := iloFixture(t, "ilo4.xml"), iloFixture(t, "login_session.json")
+	var requests []string
+	dropped := false
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !dropped {
+			dropped = true
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		requests = append(requests, r.URL.RequestURI())
+		if ua, token := r.Header.Get("User-Agent"), r.Header.Get("X-WAF-Token"); ua != userAgent || token != "secret" {
+			t.Errorf("%s: User-Agent %q, X-WAF-Token %q", r.URL, ua, token)
+		}
+		switch r.URL.Path {
+		case "/xmldata":
+			w.Write(rimp)
+		case "/json/login_session":
+			w.Write(session)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	_, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	cfg := validConfig()
+	cfg.Retries = 1
+	found, err := hpilo.Discover(context.Background(), newILODoer(&cfg), "127.0.0.1", port, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.HW != "iLO 4" || found.ServerName != "srv-gen10" {
+		t.Errorf("Discover = %+v", found)
+	}
+	want := []string{"/xmldata?item=all", "/json/login_session?null"}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
+
+// TestILODoerKeepsRedirects checks that the doer hands a redirect to
+// package ilo instead of following it.
+func TestILODoerKeepsRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.RedirectHandler("https://127.0.0.1:1/xmldata?item=all", http.StatusMovedPermanently))
+	defer srv.Close()
+	cfg := validConfig()
+	req, _ := http.NewRequest("GET", srv.URL+"/xmldata?item=all", nil)
+	resp, err := newILODoer(&cfg).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusMovedPermanently)
+	}
+}
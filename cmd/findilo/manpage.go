@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// manPageTemplate extends the kingpin man page with the sections that are
+// not derived from the flag definitions.
+var manPageTemplate = kingpin.ManPageTemplate + `.SH "EXIT STATUS"
+.TP
+\fB0\fR
+Scan completed.
+.TP
+\fB1\fR
+Invalid arguments or a fatal error.
+.SH "ENVIRONMENT"
+{{range .App.Flags}}\
+{{if .Envar}}\
+.TP
+\fB{{.Envar}}\fR
+Default for \fB--{{.Name}}\fR.
+{{end}}\
+{{end}}\
+.TP
+\fBHOME\fR
+Location of the history and cache files.
+.SH "FILES"
+.TP
+\fB~/.findilo.json\fR
+Scan history written after every scan, in JSON, see \fB--db\fR.
+Read from \fB~/.findilo.db\fR, its former name, until it is first written.
+.TP
+\fB~/.findilo.cache\fR
+XML checksums used by \fB--skip-unchanged\fR, see \fB--cache-file\fR.
+`
+
+func generateManPage(c *kingpin.ParseContext) error {
+	app := kingpin.CommandLine
+	app.Writer(os.Stdout)
+	if err := app.UsageForContextWithTemplate(c, 2, manPageTemplate); err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, "\n", " ")
+
+// writeMarkdown writes the table as a GitHub-flavored Markdown table.
+func writeMarkdown(w io.Writer, ilo []ILOInfo) error {
+	header, rows := tableRows(ilo)
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	for _, row := range append([][]string{header, sep}, rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = markdownEscaper.Replace(cell)
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
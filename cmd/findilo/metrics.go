@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanMetrics holds the results of the last completed scan for the
+// Prometheus endpoint and the /hosts of serve.
+type scanMetrics struct {
+	sync.Mutex
+	ilo      []ILOInfo
+	failed   []ILOError
+	duration time.Duration
+	scanned  time.Time
+}
+
+var metrics = &scanMetrics{}
+
+func (m *scanMetrics) update(ilo []ILOInfo, failed []ILOError, duration time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.ilo = ilo
+	m.failed = failed
+	m.duration = duration
+	m.scanned = time.Now().UTC()
+}
+
+// serveReport writes the last completed scan as the --output json
+// document.
+func (m *scanMetrics) serveReport(w http.ResponseWriter, r *http.Request) {
+	m.Lock()
+	defer m.Unlock()
+	if m.scanned.IsZero() {
+		http.Error(w, "no scan completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := writeJSON(w, m.ilo, m.failed, m.scanned); err != nil {
+		logger.Errorf("hosts: %v", err)
+	}
+}
+
+// lastDuration returns how long the last completed scan took.
+func (m *scanMetrics) lastDuration() time.Duration {
+	m.Lock()
+	defer m.Unlock()
+	return m.duration
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *scanMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.Lock()
+	defer m.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP findilo_ilo_info Discovered iLO interfaces.")
+	fmt.Fprintln(w, "# TYPE findilo_ilo_info gauge")
+	for _, info := range m.ilo {
+		fmt.Fprintf(w, "findilo_ilo_info{ip=\"%s\",hw=\"%s\",fw=\"%s\",model=\"%s\",serial=\"%s\"} 1\n",
+			labelEscaper.Replace(info.IP),
+			labelEscaper.Replace(info.HW),
+			labelEscaper.Replace(info.FW),
+			labelEscaper.Replace(info.Model),
+			labelEscaper.Replace(info.Serial))
+	}
+	fmt.Fprintln(w, "# HELP findilo_scan_duration_seconds Duration of the last completed scan.")
+	fmt.Fprintln(w, "# TYPE findilo_scan_duration_seconds gauge")
+	fmt.Fprintf(w, "findilo_scan_duration_seconds %g\n", m.duration.Seconds())
+}
+
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Errorf("metrics: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := &scanMetrics{}
+	m.update([]ILOInfo{
+		{IP: "10.0.0.1", HW: "ILO4", FW: "2.55", Model: "ProLiant DL380 Gen9", Serial: "CZ1"},
+		{IP: "10.0.0.2", HW: "ILO5", FW: "1.40", Model: `Model "X" \ 2`, Serial: "CZ2\nCZ3"},
+	}, nil, 1500*time.Millisecond)
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(raw)
+	for _, want := range []string{
+		"# TYPE findilo_ilo_info gauge\n",
+		`findilo_ilo_info{ip="10.0.0.1",hw="ILO4",fw="2.55",model="ProLiant DL380 Gen9",serial="CZ1"} 1` + "\n",
+		`findilo_ilo_info{ip="10.0.0.2",hw="ILO5",fw="1.40",model="Model \"X\" \\ 2",serial="CZ2\nCZ3"} 1` + "\n",
+		"# TYPE findilo_scan_duration_seconds gauge\n",
+		"findilo_scan_duration_seconds 1.5\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+	if n := strings.Count(body, "\n"); n != 7 {
+		t.Errorf("metrics have %d lines, want 7:\n%s", n, body)
+	}
+}
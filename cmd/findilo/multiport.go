@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hdhog/findilo/pkg/fingerprint"
+)
+
+// ipmiPort is the RMCP port of IPMI over LAN.
+const ipmiPort = 623
+
+// multiPorts are the TCP ports the iLO fingerprinter also tries with
+// --multi-port, in order.
+var multiPorts = []int{iloPort, httpsPort, httpPort}
+
+// ipmiFingerprinter reports hosts that only answer an IPMI presence ping
+// with --multi-port, without details, since IPMI does not carry the iLO
+// XML.
+type ipmiFingerprinter struct {
+	cfg *Config
+}
+
+func init() {
+	fingerprint.Register(orderIPMI, "ipmi", ipmiFingerprinter{cfg: &config})
+}
+
+func (f ipmiFingerprinter) Ports() []int {
+	if !f.cfg.MultiPort {
+		return nil
+	}
+	return []int{fingerprint.NoTCPProbe}
+}
+
+func (f ipmiFingerprinter) Identify(ctx context.Context, host string, port int) (*fingerprint.Device, bool, error) {
+	if !rmcpPing(ctx, f.cfg, host) {
+		return nil, false, nil
+	}
+	info := &ILOInfo{
+		IP:         host,
+		HW:         notAvailable,
+		FW:         notAvailable,
+		Model:      notAvailable,
+		Serial:     notAvailable,
+		DeviceType: deviceIPMI,
+		Port:       ipmiPort,
+		Warnings:   []string{"only IPMI answers"},
+	}
+	return info.device(), true, nil
+}
+
+// Enrich has nothing to add: IPMI carries no details without a login.
+func (ipmiFingerprinter) Enrich(ctx context.Context, dev *fingerprint.Device) error {
+	return nil
+}
+
+// rmcpPresencePing is an ASF presence ping: the RMCP header, then the ASF
+// IANA number 4542, message type 0x80, tag, reserved and data length.
+var rmcpPresencePing = []byte{0x06, 0x00, 0xff, 0x06, 0x00, 0x00, 0x11, 0xbe, 0x80, 0x00, 0x00, 0x00}
+
+const rmcpPresencePong = 0x40
+
+// rmcpPing reports whether the host answers an RMCP presence ping on the
+// IPMI port within the connect timeout.
+func rmcpPing(ctx context.Context, cfg *Config, host string) bool {
+	if dialLimiter.waitContext(ctx) != nil {
+		return false
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host, strconv.Itoa(ipmiPort)))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(cfg.ConnTimeout))
+	if _, err := conn.Write(rmcpPresencePing); err != nil {
+		return false
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil || n < 9 {
+		return false
+	}
+	return bytes.Equal(buf[:4], rmcpPresencePing[:4]) && buf[8] == rmcpPresencePong
+}
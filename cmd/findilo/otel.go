@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLP span kinds and status codes.
+const (
+	otelKindInternal = 1
+	otelKindClient   = 3
+	otelStatusOK     = 1
+	otelStatusError  = 2
+)
+
+type otelValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otelAttribute struct {
+	Key   string    `json:"key"`
+	Value otelValue `json:"value"`
+}
+
+type otelResource struct {
+	Attributes []otelAttribute `json:"attributes"`
+}
+
+type otelScope struct {
+	Name string `json:"name"`
+}
+
+type otelDataPoint struct {
+	Attributes   []otelAttribute `json:"attributes,omitempty"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsInt        string          `json:"asInt,omitempty"`
+	AsDouble     *float64        `json:"asDouble,omitempty"`
+}
+
+type otelMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit,omitempty"`
+	Gauge       struct {
+		DataPoints []otelDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+}
+
+type otelScopeMetrics struct {
+	Scope   otelScope    `json:"scope"`
+	Metrics []otelMetric `json:"metrics"`
+}
+
+type otelResourceMetrics struct {
+	Resource     otelResource       `json:"resource"`
+	ScopeMetrics []otelScopeMetrics `json:"scopeMetrics"`
+}
+
+type otelMetricsRequest struct {
+	ResourceMetrics []otelResourceMetrics `json:"resourceMetrics"`
+}
+
+type otelSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otelAttribute `json:"attributes,omitempty"`
+	Status            struct {
+		Code int `json:"code"`
+	} `json:"status"`
+}
+
+type otelScopeSpans struct {
+	Scope otelScope  `json:"scope"`
+	Spans []otelSpan `json:"spans"`
+}
+
+type otelResourceSpans struct {
+	Resource   otelResource     `json:"resource"`
+	ScopeSpans []otelScopeSpans `json:"scopeSpans"`
+}
+
+type otelTracesRequest struct {
+	ResourceSpans []otelResourceSpans `json:"resourceSpans"`
+}
+
+type hostSpan struct {
+	host       string
+	start, end time.Time
+	ok         bool
+}
+
+// hostSpans collects a span per probed host for the OTLP trace of a scan.
+type hostSpans struct {
+	sync.Mutex
+	spans []hostSpan
+}
+
+var tracer = &hostSpans{}
+
+func (t *hostSpans) record(host string, start time.Time, ok bool) {
+	if *otelEndpoint == "" {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.spans = append(t.spans, hostSpan{host: host, start: start, end: time.Now(), ok: ok})
+}
+
+func (t *hostSpans) take() []hostSpan {
+	t.Lock()
+	defer t.Unlock()
+	spans := t.spans
+	t.spans = nil
+	return spans
+}
+
+func otelID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func attr(key, value string) otelAttribute {
+	return otelAttribute{Key: key, Value: otelValue{StringValue: value}}
+}
+
+func otelResourceFor(service string) otelResource {
+	return otelResource{Attributes: []otelAttribute{attr("service.name", service)}}
+}
+
+// otelMetrics mirrors the Prometheus metrics of metrics.go.
+func otelMetrics(service string, ilo []ILOInfo, duration time.Duration, at time.Time) *otelMetricsRequest {
+	info := otelMetric{Name: "findilo_ilo_info", Description: "Discovered iLO interfaces."}
+	for _, i := range ilo {
+		info.Gauge.DataPoints = append(info.Gauge.DataPoints, otelDataPoint{
+			Attributes: []otelAttribute{
+				attr("ip", i.IP),
+				attr("hw", i.HW),
+				attr("fw", i.FW),
+				attr("model", i.Model),
+				attr("serial", i.Serial),
+			},
+			TimeUnixNano: unixNano(at),
+			AsInt:        "1",
+		})
+	}
+	seconds := duration.Seconds()
+	dur := otelMetric{Name: "findilo_scan_duration_seconds", Description: "Duration of the last completed scan.", Unit: "s"}
+	dur.Gauge.DataPoints = []otelDataPoint{{TimeUnixNano: unixNano(at), AsDouble: &seconds}}
+
+	return &otelMetricsRequest{ResourceMetrics: []otelResourceMetrics{{
+		Resource: otelResourceFor(service),
+		ScopeMetrics: []otelScopeMetrics{{
+			Scope:   otelScope{Name: "findilo"},
+			Metrics: []otelMetric{info, dur},
+		}},
+	}}}
+}
+
+// otelTrace builds a scan span with a child span per probed host.
+func otelTrace(service string, start, end time.Time, hosts []hostSpan) *otelTracesRequest {
+	traceID := otelID(16)
+	root := otelSpan{
+		TraceID:           traceID,
+		SpanID:            otelID(8),
+		Name:              "scan",
+		Kind:              otelKindInternal,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+	}
+	root.Status.Code = otelStatusOK
+	spans := []otelSpan{root}
+	for _, h := range hosts {
+		span := otelSpan{
+			TraceID:           traceID,
+			SpanID:            otelID(8),
+			ParentSpanID:      root.SpanID,
+			Name:              "scan host",
+			Kind:              otelKindClient,
+			StartTimeUnixNano: unixNano(h.start),
+			EndTimeUnixNano:   unixNano(h.end),
+			Attributes:        []otelAttribute{attr("net.peer.ip", h.host)},
+		}
+		span.Status.Code = otelStatusOK
+		if !h.ok {
+			span.Status.Code = otelStatusError
+		}
+		spans = append(spans, span)
+	}
+
+	return &otelTracesRequest{ResourceSpans: []otelResourceSpans{{
+		Resource: otelResourceFor(service),
+		ScopeSpans: []otelScopeSpans{{
+			Scope: otelScope{Name: "findilo"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func otelPost(endpoint, path string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(endpoint, "/") + path
+	resp, err := newHTTPClient(config).Post(url, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	logResponse(resp, body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// otelExport sends the scan trace and metrics to an OTLP collector, over
+// gRPC or HTTP by the scheme of endpoint.
+func otelExport(endpoint, service string, ilo []ILOInfo, start, end time.Time) error {
+	traces := otelTrace(service, start, end, tracer.take())
+	metrics := otelMetrics(service, ilo, end.Sub(start), end)
+	if isGRPCEndpoint(endpoint) {
+		if err := otelGRPCExport(endpoint, otelTraceService, traces.proto()); err != nil {
+			return err
+		}
+		return otelGRPCExport(endpoint, otelMetricsService, metrics.proto())
+	}
+	if err := otelPost(endpoint, "/v1/traces", traces); err != nil {
+		return err
+	}
+	return otelPost(endpoint, "/v1/metrics", metrics)
+}
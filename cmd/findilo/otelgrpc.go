@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OTLP/gRPC services of the exported requests.
+const (
+	otelTraceService   = "opentelemetry.proto.collector.trace.v1.TraceService"
+	otelMetricsService = "opentelemetry.proto.collector.metrics.v1.MetricsService"
+)
+
+// isGRPCEndpoint reports whether an --otel-endpoint is an OTLP/gRPC
+// collector: grpc:// in cleartext, grpcs:// over TLS.
+func isGRPCEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "grpc://") || strings.HasPrefix(endpoint, "grpcs://")
+}
+
+// protoBuf is a protobuf message being encoded. The OTLP requests are
+// small and fixed, so they are encoded by hand instead of pulling the
+// protobuf and gRPC modules into the build.
+type protoBuf []byte
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (b *protoBuf) tag(field, wire int) {
+	*b = binary.AppendUvarint(*b, uint64(field<<3|wire))
+}
+
+func (b *protoBuf) bytes(field int, v []byte) {
+	b.tag(field, wireBytes)
+	*b = binary.AppendUvarint(*b, uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protoBuf) message(field int, m protoBuf) {
+	b.bytes(field, m)
+}
+
+func (b *protoBuf) varint(field int, v uint64) {
+	b.tag(field, wireVarint)
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *protoBuf) fixed64(field int, v uint64) {
+	b.tag(field, wireFixed64)
+	*b = binary.LittleEndian.AppendUint64(*b, v)
+}
+
+// hexBytes decodes the hex trace and span IDs of the OTLP/JSON structs.
+func hexBytes(s string) []byte {
+	raw, _ := hex.DecodeString(s)
+	return raw
+}
+
+// nanos decodes the decimal timestamps of the OTLP/JSON structs.
+func nanos(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
+
+func (a otelAttribute) proto() protoBuf {
+	var value, b protoBuf
+	value.string(1, a.Value.StringValue)
+	b.string(1, a.Key)
+	b.message(2, value)
+	return b
+}
+
+func (r otelResource) proto() protoBuf {
+	var b protoBuf
+	for _, a := range r.Attributes {
+		b.message(1, a.proto())
+	}
+	return b
+}
+
+func (s otelScope) proto() protoBuf {
+	var b protoBuf
+	b.string(1, s.Name)
+	return b
+}
+
+func (s otelSpan) proto() protoBuf {
+	var status, b protoBuf
+	b.bytes(1, hexBytes(s.TraceID))
+	b.bytes(2, hexBytes(s.SpanID))
+	if s.ParentSpanID != "" {
+		b.bytes(4, hexBytes(s.ParentSpanID))
+	}
+	b.string(5, s.Name)
+	b.varint(6, uint64(s.Kind))
+	b.fixed64(7, nanos(s.StartTimeUnixNano))
+	b.fixed64(8, nanos(s.EndTimeUnixNano))
+	for _, a := range s.Attributes {
+		b.message(9, a.proto())
+	}
+	status.varint(3, uint64(s.Status.Code))
+	b.message(15, status)
+	return b
+}
+
+// proto encodes an ExportTraceServiceRequest.
+func (r *otelTracesRequest) proto() protoBuf {
+	var b protoBuf
+	for _, rs := range r.ResourceSpans {
+		var resource protoBuf
+		resource.message(1, rs.Resource.proto())
+		for _, ss := range rs.ScopeSpans {
+			var scope protoBuf
+			scope.message(1, ss.Scope.proto())
+			for _, s := range ss.Spans {
+				scope.message(2, s.proto())
+			}
+			resource.message(2, scope)
+		}
+		b.message(1, resource)
+	}
+	return b
+}
+
+func (p otelDataPoint) proto() protoBuf {
+	var b protoBuf
+	b.fixed64(3, nanos(p.TimeUnixNano))
+	if p.AsDouble != nil {
+		b.fixed64(4, math.Float64bits(*p.AsDouble))
+	} else {
+		n, _ := strconv.ParseInt(p.AsInt, 10, 64)
+		b.fixed64(6, uint64(n))
+	}
+	for _, a := range p.Attributes {
+		b.message(7, a.proto())
+	}
+	return b
+}
+
+func (m otelMetric) proto() protoBuf {
+	var gauge, b protoBuf
+	b.string(1, m.Name)
+	b.string(2, m.Description)
+	if m.Unit != "" {
+		b.string(3, m.Unit)
+	}
+	for _, p := range m.Gauge.DataPoints {
+		gauge.message(1, p.proto())
+	}
+	b.message(5, gauge)
+	return b
+}
+
+// proto encodes an ExportMetricsServiceRequest.
+func (r *otelMetricsRequest) proto() protoBuf {
+	var b protoBuf
+	for _, rm := range r.ResourceMetrics {
+		var resource protoBuf
+		resource.message(1, rm.Resource.proto())
+		for _, sm := range rm.ScopeMetrics {
+			var scope protoBuf
+			scope.message(1, sm.Scope.proto())
+			for _, m := range sm.Metrics {
+				scope.message(2, m.proto())
+			}
+			resource.message(2, scope)
+		}
+		b.message(1, resource)
+	}
+	return b
+}
+
+// grpcClient returns the HTTP/2 client of an OTLP/gRPC endpoint and the
+// scheme of its requests: cleartext HTTP/2 for grpc://, HTTP/2 over TLS
+// for grpcs://.
+func grpcClient(endpoint *url.URL) (*http.Client, string) {
+	c := config
+	c.InsecureTLS = false
+	tr := newTransport(c)
+	tr.Protocols = new(http.Protocols)
+	scheme := "https"
+	if endpoint.Scheme == "grpc" {
+		scheme = "http"
+		tr.Protocols.SetUnencryptedHTTP2(true)
+	} else {
+		tr.Protocols.SetHTTP2(true)
+	}
+	return &http.Client{Transport: tr, Timeout: c.HTTPTimeout}, scheme
+}
+
+// otelGRPCExport calls the Export method of an OTLP/gRPC service with msg
+// and checks the grpc-status of the reply.
+func otelGRPCExport(endpoint, service string, msg protoBuf) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	client, scheme := grpcClient(u)
+	// A gRPC message is framed by a compression flag and its length.
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+	target := scheme + "://" + u.Host + "/" + service + "/Export"
+	req, err := http.NewRequest("POST", target, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		return err
+	}
+	logResponse(resp, nil)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: %s", target, resp.Status)
+	}
+	// Errors without a reply come in the headers, the others in the
+	// trailers after the reply.
+	trailer := resp.Trailer
+	if resp.Header.Get("Grpc-Status") != "" {
+		trailer = resp.Header
+	}
+	if status := trailer.Get("Grpc-Status"); status != "0" {
+		return fmt.Errorf("POST %s: grpc-status %q: %s", target, status, trailer.Get("Grpc-Message"))
+	}
+	return nil
+}
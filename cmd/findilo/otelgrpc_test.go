@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// protoFields splits a protobuf message into its fields by number. Only
+// the length-delimited fields are kept, which is enough to walk the
+// nested OTLP messages.
+func protoFields(t *testing.T, msg []byte) map[int][][]byte {
+	fields := map[int][][]byte{}
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			t.Fatalf("bad field key in %x", msg)
+		}
+		msg = msg[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case wireVarint:
+			_, n = binary.Uvarint(msg)
+			msg = msg[n:]
+		case wireFixed64:
+			msg = msg[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			fields[field] = append(fields[field], msg[n:n+int(size)])
+			msg = msg[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return fields
+}
+
+// grpcServer is an h2c server that answers the Export calls with
+// grpc-status status and keeps the message of the last call.
+func grpcServer(t *testing.T, status string, last *[]byte, path *string) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("request %s with content type %q, want HTTP/2 gRPC", r.Proto, r.Header.Get("Content-Type"))
+		}
+		frame, _ := ioutil.ReadAll(r.Body)
+		if len(frame) < 5 || int(binary.BigEndian.Uint32(frame[1:5])) != len(frame)-5 {
+			t.Errorf("bad gRPC frame %x", frame)
+		} else {
+			*last = frame[5:]
+		}
+		*path = r.URL.Path
+		w.Header().Set("Content-Type", "application/grpc")
+		if status != "0" {
+			// A trailers-only reply, as servers send for errors.
+			w.Header().Set("Grpc-Status", status)
+			w.Header().Set("Grpc-Message", "unavailable")
+			return
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOTelGRPCExportTraces(t *testing.T) {
+	var last []byte
+	var path string
+	srv := grpcServer(t, "0", &last, &path)
+	endpoint := "grpc://" + strings.TrimPrefix(srv.URL, "http://")
+
+	start := time.Now()
+	hosts := []hostSpan{{host: "10.0.0.1", start: start, end: start.Add(time.Second), ok: true}}
+	if err := otelGRPCExport(endpoint, otelTraceService, otelTrace("findilo-test", start, start.Add(time.Second), hosts).proto()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/" + otelTraceService + "/Export"; path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	resourceSpans := protoFields(t, last)[1]
+	if len(resourceSpans) != 1 {
+		t.Fatalf("%d resource spans, want 1", len(resourceSpans))
+	}
+	rs := protoFields(t, resourceSpans[0])
+	attr := protoFields(t, protoFields(t, rs[1][0])[1][0])
+	if key := string(attr[1][0]); key != "service.name" {
+		t.Errorf("resource attribute %q, want service.name", key)
+	}
+	if value := string(protoFields(t, attr[2][0])[1][0]); value != "findilo-test" {
+		t.Errorf("service.name = %q, want findilo-test", value)
+	}
+	spans := protoFields(t, rs[2][0])[2]
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want the scan and one host", len(spans))
+	}
+	root, host := protoFields(t, spans[0]), protoFields(t, spans[1])
+	if len(root[1][0]) != 16 || len(root[2][0]) != 8 {
+		t.Errorf("trace and span IDs have %d and %d bytes, want 16 and 8", len(root[1][0]), len(root[2][0]))
+	}
+	if string(root[5][0]) != "scan" || string(host[5][0]) != "scan host" {
+		t.Errorf("span names %q and %q", root[5][0], host[5][0])
+	}
+	if string(host[4][0]) != string(root[2][0]) {
+		t.Error("the host span is not a child of the scan span")
+	}
+}
+
+func TestOTelGRPCExportStatus(t *testing.T) {
+	var last []byte
+	var path string
+	srv := grpcServer(t, "14", &last, &path)
+	endpoint := "grpc://" + strings.TrimPrefix(srv.URL, "http://")
+	err := otelGRPCExport(endpoint, otelMetricsService, otelMetrics("findilo", nil, time.Second, time.Now()).proto())
+	if err == nil || !strings.Contains(err.Error(), `grpc-status "14"`) {
+		t.Errorf("err = %v, want grpc-status 14", err)
+	}
+}
+
+func TestValidateOTelEndpoint(t *testing.T) {
+	for endpoint, ok := range map[string]bool{
+		"":                      true,
+		"grpc://otelcol:4317":   true,
+		"grpcs://otelcol:4317":  true,
+		"http://otelcol:4318":   true,
+		"https://otelcol:4318":  true,
+		"otelcol:4317":          false,
+		"tcp://otelcol:4317":    false,
+		"unix:///run/otel.sock": false,
+	} {
+		cfg := validConfig()
+		cfg.OTelEndpoint = endpoint
+		if err := validateFlags(cfg); (err == nil) != ok {
+			t.Errorf("validateFlags(--otel-endpoint %q) = %v", endpoint, err)
+		}
+	}
+}
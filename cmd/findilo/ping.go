@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+// pingWait is how long replies are awaited after the last echo was sent.
+const pingWait = 2 * time.Second
+
+const (
+	icmpEchoRequest = 8
+	icmpEchoReply   = 0
+)
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+func icmpEcho(id, seq uint16) []byte {
+	msg := make([]byte, 8, 16)
+	msg[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	msg = append(msg, "findilo"...)
+	binary.BigEndian.PutUint16(msg[2:], icmpChecksum(msg))
+	return msg
+}
+
+// listenICMP opens a raw ICMP socket, which needs root or CAP_NET_RAW, or
+// else an unprivileged ICMP datagram socket. dgram reports the latter.
+func listenICMP() (conn net.PacketConn, dgram bool, err error) {
+	conn, err = net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, dgramErr := listenDatagramICMP()
+	if dgramErr != nil {
+		logger.Debugf("ping: %v", dgramErr)
+		return nil, false, err
+	}
+	return conn, true, nil
+}
+
+// pingSweep sends an ICMP echo to every IPv4 address and returns the ones
+// that answered. IPv6 addresses are returned unchanged. Without an ICMP
+// socket the error is returned.
+func pingSweep(ips scanner.Targets) ([]string, error) {
+	conn, dgram, err := listenICMP()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	id := uint16(os.Getpid())
+
+	var mu sync.Mutex
+	alive := map[string]bool{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 8 || buf[0] != icmpEchoReply || (!dgram && binary.BigEndian.Uint16(buf[4:]) != id) {
+				continue
+			}
+			from := addr.String()
+			if udp, ok := addr.(*net.UDPAddr); ok {
+				from = udp.IP.String()
+			}
+			mu.Lock()
+			alive[from] = true
+			mu.Unlock()
+		}
+	}()
+
+	res := []string{}
+	seq := uint16(0)
+	ips.Each(func(ip string) bool {
+		addr := net.ParseIP(ip)
+		if addr.To4() == nil {
+			res = append(res, ip)
+			return true
+		}
+		var dst net.Addr = &net.IPAddr{IP: addr}
+		if dgram {
+			dst = &net.UDPAddr{IP: addr}
+		}
+		dialLimiter.wait()
+		if _, err := conn.WriteTo(icmpEcho(id, seq), dst); err != nil {
+			logger.Debugf("%s: ping: %v", ip, err)
+		}
+		seq++
+		return true
+	})
+	conn.SetReadDeadline(time.Now().Add(pingWait))
+	<-done
+
+	for ip := range alive {
+		if ips.Contains(ip) {
+			res = append(res, ip)
+		}
+	}
+	logger.Infof("ping: %d of %d hosts answered", len(res), ips.Count())
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/cheggaaa/pb"
+	"golang.org/x/term"
+)
+
+const (
+	progressLogInterval = 5 * time.Second
+	barRefreshInterval  = 200 * time.Millisecond
+	rateWindowLength    = 5 * time.Second
+)
+
+// scanCounters counts the probed hosts and the discovered iLOs.
+type scanCounters struct {
+	scanned int64
+	found   int64
+}
+
+type rateSample struct {
+	at      time.Time
+	scanned int64
+}
+
+// rateWindow computes the scan rate over the last rateWindowLength.
+type rateWindow struct {
+	samples []rateSample
+}
+
+func (w *rateWindow) add(at time.Time, scanned int64) float64 {
+	w.samples = append(w.samples, rateSample{at, scanned})
+	for len(w.samples) > 2 && at.Sub(w.samples[1].at) >= rateWindowLength {
+		w.samples = w.samples[1:]
+	}
+	first := w.samples[0]
+	elapsed := at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(scanned-first.scanned) / elapsed
+}
+
+// isTerminal reports whether f is attached to a terminal. Other character
+// devices, like /dev/null, are not.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// canDrawBar reports whether stdout is a terminal that the progress bar
+// can redraw in place. Elsewhere, as under cron, CI or docker logs, the
+// progress is logged instead. With --output jsonl the hosts are streamed
+// to stdout, so the bar would break their lines.
+func canDrawBar() bool {
+	return *output != "jsonl" && isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb"
+}
+
+// updateBar redraws a manually updated bar with the found count and the
+// scan rate until stop is closed, then closes done.
+func updateBar(bar *pb.ProgressBar, counters *scanCounters, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(barRefreshInterval)
+	defer ticker.Stop()
+	window := &rateWindow{}
+	postfix := func() {
+		rate := window.add(time.Now(), atomic.LoadInt64(&counters.scanned))
+		bar.Postfix(fmt.Sprintf(" | Found: %d | %.0f/s", atomic.LoadInt64(&counters.found), rate))
+	}
+	for {
+		postfix()
+		bar.Update()
+		select {
+		case <-stop:
+			// The last redraw is left to bar.Finish.
+			postfix()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logProgress replaces the progress bar when it is suppressed, printing the
+// number of scanned hosts to stderr until stop is closed.
+func logProgress(counters *scanCounters, total int, stop chan struct{}) {
+	ticker := time.NewTicker(progressLogInterval)
+	defer ticker.Stop()
+	window := &rateWindow{}
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			scanned := atomic.LoadInt64(&counters.scanned)
+			rate := window.add(now, scanned)
+			fmt.Fprintf(os.Stderr, "Scanned %d/%d hosts, found %d, %.0f/s\n", scanned, total, atomic.LoadInt64(&counters.found), rate)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxRate is the highest --rate, one connection per microsecond; above it
+// the ticker interval would round down to zero.
+const maxRate = 1000000
+
+// rateLimiter lets at most rate callers per second through wait. It is
+// shared by all workers, so probes, pings and HTTP requests draw from the
+// same budget; a nil limiter does not limit.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{ticker: time.NewTicker(time.Second / time.Duration(rate))}
+}
+
+// wait blocks until the next tick. It is only for the --arp and --ping
+// sweeps before the scan; scan workers use waitContext.
+func (r *rateLimiter) wait() {
+	if r == nil {
+		return
+	}
+	<-r.ticker.C
+}
+
+// limitTransport waits for dialLimiter before every request. Requests use
+// no keep-alive, so each one is a new connection. The timeout applies to
+// each request from the end of the wait, in place of http.Client.Timeout.
+type limitTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := dialLimiter.waitContext(req.Context()); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		logger.hostf(levelDebug, logFields{Host: req.URL.Hostname(), Stage: "http", Duration: time.Since(start)},
+			"%s %s: %v", req.Method, req.URL, err)
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout of its request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// waitContext is wait that gives up when ctx is done.
+func (r *rateLimiter) waitContext(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	select {
+	case <-r.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *rateLimiter) stop() {
+	if r == nil {
+		return
+	}
+	r.ticker.Stop()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	r := newRateLimiter(10)
+	defer r.stop()
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if err := r.waitContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 1900*time.Millisecond || elapsed > 2500*time.Millisecond {
+		t.Errorf("20 waits at rate 10 took %v, want about 2s", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	r := newRateLimiter(1)
+	defer r.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := r.waitContext(ctx); err != context.Canceled {
+		t.Errorf("waitContext = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("canceled wait took %v", elapsed)
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	if r := newRateLimiter(0); r != nil {
+		t.Errorf("newRateLimiter(0) = %v, want nil", r)
+	}
+	var r *rateLimiter
+	if err := r.waitContext(context.Background()); err != nil {
+		t.Errorf("nil limiter: %v", err)
+	}
+}
+
+func TestValidateRate(t *testing.T) {
+	for _, tt := range []struct {
+		rate int
+		ok   bool
+	}{
+		{0, true},
+		{100, true},
+		{maxRate, true},
+		{-1, false},
+		{maxRate + 1, false},
+		{2000000000, false},
+	} {
+		cfg := validConfig()
+		cfg.Rate = tt.rate
+		if err := validateFlags(cfg); (err == nil) != tt.ok {
+			t.Errorf("validateFlags(rate %d) = %v", tt.rate, err)
+		}
+	}
+}
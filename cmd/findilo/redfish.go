@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+// RedfishCollection ...
+type RedfishCollection struct {
+	Members []struct {
+		ID string `json:"@odata.id"`
+	} `json:"Members"`
+}
+
+// EthernetInterface ...
+type EthernetInterface struct {
+	Oem struct {
+		Hpe struct {
+			TeamMode       string `json:"TeamMode"`
+			BondingEnabled bool   `json:"BondingEnabled"`
+		} `json:"Hpe"`
+	} `json:"Oem"`
+}
+
+// redfishRequest sends a Redfish request with the configured credentials.
+// A non-nil in is sent as the JSON body, a non-nil out receives the
+// decoded response.
+func redfishRequest(ctx context.Context, cfg *Config, method, ip, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	url := hpilo.URL("https", ip, 0, path)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cfg.Username != "" {
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+	resp, err := iloClient(cfg).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	logResponse(resp, raw)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return err
+	}
+	logger.Debugf("%s%s: %+v", ip, path, out)
+	return nil
+}
+
+const defaultSessionsPath = "/redfish/v1/SessionService/Sessions"
+
+// createSession logs in to the sessions collection at path and returns the
+// X-Auth-Token and the URL of the new session.
+func createSession(ctx context.Context, cfg *Config, ip, path, user, pass string) (string, string, error) {
+	raw, err := json.Marshal(map[string]string{"UserName": user, "Password": pass})
+	if err != nil {
+		return "", "", err
+	}
+	url := hpilo.URL("https", ip, 0, path)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(raw))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := iloClient(cfg).Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	logResponse(resp, body)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("POST %s: %s", path, resp.Status)
+	}
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return "", "", fmt.Errorf("POST %s: no X-Auth-Token in response", path)
+	}
+	return token, resp.Header.Get("Location"), nil
+}
+
+// deleteSession logs out, so that scans do not use up the iLO sessions.
+// It logs out even when ctx is done.
+func deleteSession(ctx context.Context, cfg *Config, ip, location, token string) error {
+	if location == "" {
+		return nil
+	}
+	if strings.HasPrefix(location, "/") {
+		location = hpilo.URL("https", ip, 0, location)
+	}
+	req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), "DELETE", location, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", token)
+	resp, err := iloClient(cfg).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	logResponse(resp, nil)
+	return nil
+}
+
+// requestRedfish fetches a Redfish resource and decodes it into v.
+func requestRedfish(ctx context.Context, cfg *Config, ip, path string, v interface{}) error {
+	return redfishRequest(ctx, cfg, "GET", ip, path, nil, v)
+}
+
+// patchRedfish updates the properties of a Redfish resource set in v.
+func patchRedfish(ctx context.Context, cfg *Config, ip, path string, v interface{}) error {
+	return redfishRequest(ctx, cfg, "PATCH", ip, path, v, nil)
+}
+
+// ServiceRoot ...
+type ServiceRoot struct {
+	RedfishVersion string `json:"RedfishVersion"`
+	Product        string `json:"Product"`
+	Vendor         string `json:"Vendor"`
+	Links          struct {
+		Sessions struct {
+			ID string `json:"@odata.id"`
+		} `json:"Sessions"`
+	} `json:"Links"`
+}
+
+// requestServiceRoot reads the Redfish version and product from the
+// service root, which needs no authentication.
+func requestServiceRoot(ctx context.Context, cfg *Config, ip string, info *ILOInfo) error {
+	root := &ServiceRoot{}
+	if err := requestRedfish(ctx, cfg, ip, "/redfish/v1", root); err != nil {
+		return err
+	}
+	info.APIVersion = root.RedfishVersion
+	info.RedfishProduct = root.Product
+	info.sessionsPath = root.Links.Sessions.ID
+	logger.Debugf("%s: Redfish %s, %s %s, sessions at %s", ip, root.RedfishVersion, root.Vendor, root.Product, info.sessionsPath)
+	return nil
+}
+
+// requestNICTeam checks the manager ethernet interfaces for a teaming or
+// bonding configuration in the Hpe OEM extension.
+func requestNICTeam(ctx context.Context, cfg *Config, ip string, info *ILOInfo) error {
+	ifaces := &RedfishCollection{}
+	if err := requestRedfish(ctx, cfg, ip, "/redfish/v1/Managers/1/EthernetInterfaces", ifaces); err != nil {
+		return err
+	}
+	for _, member := range ifaces.Members {
+		iface := &EthernetInterface{}
+		if err := requestRedfish(ctx, cfg, ip, member.ID, iface); err != nil {
+			return err
+		}
+		hpe := iface.Oem.Hpe
+		if hpe.BondingEnabled || hpe.TeamMode != "" {
+			info.NICTeamEnabled = true
+			info.NICTeamMode = hpe.TeamMode
+			if info.NICTeamMode == "" {
+				info.NICTeamMode = "Bonding"
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (i *ILOInfo) nicTeam() string {
+	if i.NICTeamEnabled {
+		return i.NICTeamMode
+	}
+	return "off"
+}
+
+// ComputerSystem ...
+type ComputerSystem struct {
+	IndicatorLED string `json:"IndicatorLED"`
+	PowerState   string `json:"PowerState"`
+	Status       struct {
+		IndicatorLED string `json:"IndicatorLED"`
+	} `json:"Status"`
+}
+
+// requestSystem reads the indicator LED and the power state of the server.
+func requestSystem(ctx context.Context, cfg *Config, ip string, info *ILOInfo) error {
+	system := &ComputerSystem{}
+	if err := requestRedfish(ctx, cfg, ip, "/redfish/v1/Systems/1", system); err != nil {
+		return err
+	}
+	info.LEDState = system.IndicatorLED
+	if info.LEDState == "" {
+		info.LEDState = system.Status.IndicatorLED
+	}
+	if info.LEDState == "" {
+		info.LEDState = notAvailable
+	}
+	info.PowerState = orNotAvailable(system.PowerState)
+	return nil
+}
+
+func filterLEDBlinking(ilo []ILOInfo) []ILOInfo {
+	res := []ILOInfo{}
+	for _, info := range ilo {
+		if info.LEDState == "Blinking" {
+			res = append(res, info)
+		}
+	}
+	return res
+}
+
+// SessionService ...
+type SessionService struct {
+	SessionTimeout int `json:"SessionTimeout"`
+}
+
+const sessionServicePath = "/redfish/v1/SessionService"
+
+// requestSessionTimeout reads the session timeout, which Redfish reports in
+// seconds. A positive setMinutes is written first.
+func requestSessionTimeout(ctx context.Context, cfg *Config, ip string, info *ILOInfo, setMinutes int) error {
+	if setMinutes > 0 {
+		patch := &SessionService{SessionTimeout: setMinutes * 60}
+		if err := patchRedfish(ctx, cfg, ip, sessionServicePath, patch); err != nil {
+			return err
+		}
+	}
+	service := &SessionService{}
+	if err := requestRedfish(ctx, cfg, ip, sessionServicePath, service); err != nil {
+		return err
+	}
+	info.SessionTimeoutMinutes = service.SessionTimeout / 60
+	return nil
+}
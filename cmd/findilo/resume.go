@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+// checkpointInterval is how often the state file is rewritten during a
+// scan.
+const checkpointInterval = 10 * time.Second
+
+// scanState is the state file of --state-file and --resume. Failures are
+// not kept: a host that failed is scanned again on resume only if it was
+// still in flight.
+type scanState struct {
+	Targets []string  `json:"targets"`
+	Scanned []string  `json:"scanned"`
+	Hosts   []ILOInfo `json:"hosts"`
+}
+
+// scanProgress records which targets are done and the hosts found, and
+// saves them to the state file. It is shared by the workers; a nil
+// progress records nothing.
+type scanProgress struct {
+	path    string
+	targets scanner.Targets
+	// prevHosts are the hosts found before the scan was resumed.
+	prevHosts []ILOInfo
+
+	mu      sync.Mutex
+	scanned scanner.Targets
+	batch   []string
+	hosts   []ILOInfo
+}
+
+func newScanProgress(path string, targets scanner.Targets) *scanProgress {
+	return &scanProgress{path: path, targets: targets, hosts: []ILOInfo{}}
+}
+
+// loadScanProgress reads the state file of an interrupted scan and
+// returns the progress with the targets still to scan.
+func loadScanProgress(path string) (*scanProgress, scanner.Targets, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, scanner.Targets{}, err
+	}
+	state := &scanState{}
+	if err := json.Unmarshal(raw, state); err != nil {
+		return nil, scanner.Targets{}, fmt.Errorf("%s: %v", path, err)
+	}
+	targets, err := scanner.ParseRanges(state.Targets)
+	if err != nil {
+		return nil, scanner.Targets{}, fmt.Errorf("%s: %v", path, err)
+	}
+	scanned, err := scanner.ParseRanges(state.Scanned)
+	if err != nil {
+		return nil, scanner.Targets{}, fmt.Errorf("%s: %v", path, err)
+	}
+	p := &scanProgress{path: path, targets: targets, scanned: scanned, prevHosts: state.Hosts, hosts: state.Hosts}
+	return p, targets.Minus(scanned), nil
+}
+
+func (p *scanProgress) markScanned(host string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.batch = append(p.batch, host)
+	p.mu.Unlock()
+}
+
+func (p *scanProgress) addHost(info ILOInfo) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.hosts = append(p.hosts, info)
+	p.mu.Unlock()
+}
+
+// save writes the state file through a temporary file, so that an
+// interrupted write leaves the previous state.
+func (p *scanProgress) save() error {
+	p.mu.Lock()
+	p.scanned = p.scanned.Union(scanner.TargetsOf(p.batch))
+	p.batch = nil
+	raw, err := json.Marshal(&scanState{
+		Targets: p.targets.Ranges(),
+		Scanned: p.scanned.Ranges(),
+		Hosts:   p.hosts,
+	})
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, raw)
+}
+
+// finish saves the state of an interrupted scan and removes the state
+// file of a complete one.
+func (p *scanProgress) finish(interrupted bool) {
+	if !interrupted {
+		if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+			logger.Errorf("state file: %v", err)
+		}
+		return
+	}
+	if err := p.save(); err != nil {
+		logger.Errorf("state file: %v", err)
+		return
+	}
+	logger.Warnf("scan state saved, continue with --resume %s", p.path)
+}
+
+// autosave saves the state every checkpointInterval until the returned
+// function is called.
+func (p *scanProgress) autosave() func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.save(); err != nil {
+					logger.Errorf("state file: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// retryBackoff is the pause before the first retry; it doubles with every
+// further attempt.
+const retryBackoff = 100 * time.Millisecond
+
+// retry calls f until it succeeds, at most cfg.Retries more times after
+// the first call. A refused connection is a closed port, not a busy one,
+// and is returned at once, as is the error when ctx is done.
+func retry(ctx context.Context, cfg *Config, f func() error) error {
+	err := f()
+	pause := retryBackoff
+	for i := 0; i < cfg.Retries && err != nil && !errors.Is(err, syscall.ECONNREFUSED); i++ {
+		select {
+		case <-time.After(pause):
+		case <-ctx.Done():
+			return err
+		}
+		pause *= 2
+		err = f()
+	}
+	return err
+}
+
+// getRetry is client.Get with the retries of cfg.
+func getRetry(ctx context.Context, cfg *Config, client *http.Client, url string) (*http.Response, error) {
+	var resp *http.Response
+	err := retry(ctx, cfg, func() error {
+		var err error
+		resp, err = getContext(ctx, client, url)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !solaris && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!solaris,!dragonfly
+
+package main
+
+// maxWorkers returns 0 where the open file limit cannot be queried.
+func maxWorkers() int {
+	return 0
+}
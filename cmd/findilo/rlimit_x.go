@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || solaris || dragonfly
+// +build linux darwin freebsd netbsd openbsd solaris dragonfly
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// maxWorkers returns how many workers fit into the open file limit, or 0
+// when the limit is unknown. On Linux the soft limit is raised to the hard
+// limit first.
+func maxWorkers() int {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
+		return 0
+	}
+	if runtime.GOOS == "linux" && rlim.Cur < rlim.Max {
+		raised := rlim
+		raised.Cur = raised.Max
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &raised); err == nil {
+			rlim = raised
+		}
+	}
+	return workersForFiles(uint64(rlim.Cur))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+// maxMissingSerials is how many hosts may lack a serial number before
+// the XML endpoint is suspected of returning partial data.
+const maxMissingSerials = 5
+
+// warnDuplicateSerials warns about serial numbers reported by more than
+// one host, which points to cloned images or firmware bugs, and about many
+// hosts without a serial.
+func warnDuplicateSerials(ilo []ILOInfo) {
+	hosts := map[string][]int{}
+	missing := 0
+	for i, info := range ilo {
+		serial := strings.TrimSpace(info.Serial)
+		if serial == "" || serial == notAvailable {
+			missing++
+			continue
+		}
+		hosts[serial] = append(hosts[serial], i)
+	}
+	serials := []string{}
+	for serial, idx := range hosts {
+		if len(idx) > 1 {
+			serials = append(serials, serial)
+		}
+	}
+	sort.Strings(serials)
+	for _, serial := range serials {
+		idx := hosts[serial]
+		sort.Slice(idx, func(a, b int) bool {
+			ka, kb := scanner.IPKey(ilo[idx[a]].IP), scanner.IPKey(ilo[idx[b]].IP)
+			return bytes.Compare(ka[:], kb[:]) < 0
+		})
+		ips := []string{}
+		for _, i := range idx {
+			ips = append(ips, ilo[i].IP)
+			ilo[i].Warnings = append(ilo[i].Warnings, "duplicate serial")
+		}
+		logger.Warnf("serial %s is reported by %d hosts: %s", serial, len(ips), strings.Join(ips, ", "))
+	}
+	if missing > maxMissingSerials {
+		logger.Warnf("%d hosts report no serial number, /xmldata may be returning partial data", missing)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Logical drive states in LogicalDrive.Status.
+const (
+	driveOK       = "OK"
+	driveDegraded = "Degraded"
+	driveFailed   = "Failed"
+)
+
+// LogicalDrive is a logical drive of a Smart Array controller.
+type LogicalDrive struct {
+	LogicalDriveNumber int    `json:"logical_drive_number"`
+	Raid               string `json:"raid"`
+	CapacityGiB        int    `json:"capacity_gib"`
+	Status             string `json:"status"`
+	FaultTolerance     string `json:"fault_tolerance"`
+}
+
+type ssaLogicalDrive struct {
+	LogicalDriveNumber int    `json:"LogicalDriveNumber"`
+	Raid               string `json:"Raid"`
+	CapacityGiB        int    `json:"CapacityGiB"`
+	CapacityMiB        int    `json:"CapacityMiB"`
+	FaultTolerance     string `json:"FaultTolerance"`
+	Status             struct {
+		Health string `json:"Health"`
+	} `json:"Status"`
+}
+
+// driveStatus maps the Redfish health of a drive to OK, Degraded or Failed.
+func driveStatus(health string) string {
+	switch health {
+	case "OK":
+		return driveOK
+	case "Warning":
+		return driveDegraded
+	case "Critical":
+		return driveFailed
+	}
+	return notAvailable
+}
+
+// requestLogicalDrives collects the logical drives of all Smart Array
+// controllers of the system.
+func requestLogicalDrives(ctx context.Context, cfg *Config, ip string, info *ILOInfo) error {
+	controllers := &RedfishCollection{}
+	if err := requestRedfish(ctx, cfg, ip, "/redfish/v1/Systems/1/SmartStorage/ArrayControllers", controllers); err != nil {
+		return err
+	}
+	for _, ctrl := range controllers.Members {
+		drives := &RedfishCollection{}
+		if err := requestRedfish(ctx, cfg, ip, strings.TrimSuffix(ctrl.ID, "/")+"/LogicalDrives", drives); err != nil {
+			return err
+		}
+		for _, member := range drives.Members {
+			drive := &ssaLogicalDrive{}
+			if err := requestRedfish(ctx, cfg, ip, member.ID, drive); err != nil {
+				return err
+			}
+			capacity := drive.CapacityGiB
+			if capacity == 0 {
+				capacity = drive.CapacityMiB / 1024
+			}
+			info.SSALogicalDrives = append(info.SSALogicalDrives, LogicalDrive{
+				LogicalDriveNumber: drive.LogicalDriveNumber,
+				Raid:               drive.Raid,
+				CapacityGiB:        capacity,
+				Status:             driveStatus(drive.Status.Health),
+				FaultTolerance:     drive.FaultTolerance,
+			})
+		}
+	}
+	return nil
+}
+
+// alertDegradedDrives logs an alert and adds a warning for every logical
+// drive that is not OK.
+func alertDegradedDrives(info *ILOInfo) {
+	for _, d := range info.SSALogicalDrives {
+		if d.Status == driveDegraded || d.Status == driveFailed {
+			logger.Warnf("%s: logical drive %d (RAID %s) is %s", info.IP, d.LogicalDriveNumber, d.Raid, d.Status)
+			info.Warnings = append(info.Warnings, fmt.Sprintf("logical drive %d %s", d.LogicalDriveNumber, d.Status))
+		}
+	}
+}
+
+func logicalDrivesString(info ILOInfo) string {
+	drives := []string{}
+	for _, d := range info.SSALogicalDrives {
+		drives = append(drives, fmt.Sprintf("%d:RAID%s %dG %s", d.LogicalDriveNumber, d.Raid, d.CapacityGiB, d.Status))
+	}
+	return strings.Join(drives, ", ")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+const summaryUnknown = "Unknown"
+
+// summarize counts the hosts per iLO generation. Hosts without a known
+// generation are counted as Unknown.
+func summarize(ilo []ILOInfo) map[string]int {
+	counts := map[string]int{}
+	for _, info := range ilo {
+		gen := hpilo.Generation(info.HW)
+		if gen == 0 {
+			counts[summaryUnknown]++
+			continue
+		}
+		counts[fmt.Sprintf("iLO %d", gen)]++
+	}
+	return counts
+}
+
+// summaryLine formats the counts ordered by generation, followed by the
+// total and the number of hosts whose XML could not be parsed.
+func summaryLine(ilo []ILOInfo, failed []ILOError) string {
+	counts := summarize(ilo)
+	keys := []string{}
+	for k := range counts {
+		if k != summaryUnknown {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return hpilo.Generation(keys[i]) < hpilo.Generation(keys[j])
+	})
+	if counts[summaryUnknown] > 0 {
+		keys = append(keys, summaryUnknown)
+	}
+	parts := []string{}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", k, counts[k]))
+	}
+	parts = append(parts, fmt.Sprintf("Total: %d", len(ilo)))
+	parseFailed := 0
+	for _, e := range failed {
+		if e.Phase == phaseParse {
+			parseFailed++
+		}
+	}
+	if parseFailed > 0 {
+		parts = append(parts, fmt.Sprintf("XML parse failed: %d", parseFailed))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// hostsRender writes the IP of every host on its own line, for piping into
+// other tools.
+func hostsRender(w io.Writer, ilo []ILOInfo) {
+	sort.Slice(ilo, func(i, j int) bool {
+		ki, kj := scanner.IPKey(ilo[i].IP), scanner.IPKey(ilo[j].IP)
+		return bytes.Compare(ki[:], kj[:]) < 0
+	})
+	for _, info := range ilo {
+		fmt.Fprintln(w, info.IP)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hdhog/findilo/pkg/fingerprint"
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+)
+
+const (
+	httpPort  = 80
+	httpsPort = 443
+)
+
+// Device types reported in ILOInfo.DeviceType.
+const (
+	deviceILO     = "iLO"
+	deviceSwitch  = "Switch"
+	deviceIPMI    = "IPMI"
+	deviceUnknown = "Unknown"
+)
+
+// SwitchStatus is the /rest/v1/system/status resource of ArubaOS-Switch
+// (ProCurve) devices.
+type SwitchStatus struct {
+	Name            string `json:"name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	HardwareRev     string `json:"hardware_revision"`
+	ProductModel    string `json:"product_model"`
+}
+
+func orNotAvailable(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return notAvailable
+	}
+	return s
+}
+
+// switchFingerprinter finds HPE ProCurve/Aruba switches on the HTTPS
+// port, with --include-switches.
+type switchFingerprinter struct {
+	cfg *Config
+}
+
+func init() {
+	fingerprint.Register(orderSwitch, "switch", switchFingerprinter{cfg: &config})
+}
+
+func (f switchFingerprinter) Ports() []int {
+	if !f.cfg.WithSwitches {
+		return nil
+	}
+	return []int{httpsPort}
+}
+
+func (f switchFingerprinter) Identify(ctx context.Context, host string, port int) (*fingerprint.Device, bool, error) {
+	info, err := requestSwitch(ctx, f.cfg, host)
+	if err != nil {
+		logger.Debugf("%s: %v", host, err)
+		return nil, false, nil
+	}
+	return info.device(), true, nil
+}
+
+// Enrich has nothing to add: the status resource holds all details.
+func (switchFingerprinter) Enrich(ctx context.Context, dev *fingerprint.Device) error {
+	return nil
+}
+
+// requestSwitch identifies an HPE ProCurve/Aruba switch by its web
+// interface headers and REST API.
+func requestSwitch(ctx context.Context, cfg *Config, ip string) (*ILOInfo, error) {
+	client := iloClient(cfg)
+	url := hpilo.URL("https", ip, 0, "/")
+	resp, err := getContext(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	logResponse(resp, nil)
+	if resp.Header.Get("X-Frame-Options") == "" {
+		return nil, fmt.Errorf("%s: not a switch management interface", ip)
+	}
+
+	url = hpilo.URL("https", ip, 0, "/rest/v1/system/status")
+	resp, err = getContext(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logResponse(resp, raw)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: /rest/v1: %s", ip, resp.Status)
+	}
+	status := &SwitchStatus{}
+	if err := json.Unmarshal(raw, status); err != nil {
+		return nil, err
+	}
+	logger.Debugf("%s: %+v", ip, *status)
+	return &ILOInfo{
+		IP:         ip,
+		HW:         orNotAvailable(status.HardwareRev),
+		FW:         orNotAvailable(status.FirmwareVersion),
+		Model:      orNotAvailable(status.ProductModel),
+		Serial:     strings.TrimSpace(status.SerialNumber),
+		ServerName: strings.TrimSpace(status.Name),
+		DeviceType: deviceSwitch,
+	}, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build metadata, set at link time:
+//
+//	go build -ldflags "-X main.buildVersion=1.2.0 -X main.buildCommit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	buildVersion = "dev"
+	buildCommit  = "unknown"
+	buildDate    = "unknown"
+)
+
+// versionString describes the build for --version and the version
+// command.
+func versionString() string {
+	return fmt.Sprintf("findilo %s (commit %s, built %s, %s %s/%s)", buildVersion, buildCommit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func versionCommand() {
+	fmt.Println(versionString())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hdhog/findilo/pkg/scanner"
+)
+
+// diffScans compares two scans keyed on IP and returns one line per new,
+// disappeared or reflashed host.
+func diffScans(prev, cur []ILOInfo) []string {
+	before := make(map[string]ILOInfo, len(prev))
+	for _, info := range prev {
+		before[info.IP] = info
+	}
+	after := make(map[string]ILOInfo, len(cur))
+	for _, info := range cur {
+		after[info.IP] = info
+	}
+	lines := []string{}
+	for _, info := range cur {
+		old, ok := before[info.IP]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("+ %s %s %s %s", info.IP, info.HW, info.FW, info.Serial))
+		case old.FW != info.FW:
+			lines = append(lines, fmt.Sprintf("~ %s fw %s -> %s", info.IP, old.FW, info.FW))
+		}
+	}
+	for _, info := range prev {
+		if _, ok := after[info.IP]; !ok {
+			lines = append(lines, fmt.Sprintf("- %s %s %s %s", info.IP, info.HW, info.FW, info.Serial))
+		}
+	}
+	return lines
+}
+
+// watch rescans ips every interval and prints the changes between scans,
+// recording each scan in the history. When ctx is done, also in the middle
+// of a rescan, the last complete scan is rendered as a full table.
+func watch(ctx context.Context, ips scanner.Targets, interval time.Duration) {
+	current, failed := runScan(ctx, ips, true)
+	report(current, failed)
+	if ctx.Err() != nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			tableRender(current)
+			fmt.Println("")
+			return
+		case <-time.After(interval):
+		}
+
+		next, _ := runScan(ctx, ips, false)
+		if ctx.Err() != nil {
+			// The rescan was cut short; its hosts are not a complete state.
+			tableRender(current)
+			fmt.Println("")
+			return
+		}
+		now := time.Now()
+		recordHistory(*dbPath, next, now)
+		for _, line := range diffScans(current, next) {
+			fmt.Printf("%s %s\n", now.Format("15:04:05"), line)
+		}
+		current = next
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+const (
+	// reservedFiles are kept for stdio, the cache and history files.
+	reservedFiles = 50
+	// filesPerWorker is the number of connections a worker can hold at once.
+	filesPerWorker = 3
+)
+
+func workersForFiles(limit uint64) int {
+	if limit <= reservedFiles+filesPerWorker {
+		return 1
+	}
+	return int((limit - reservedFiles) / filesPerWorker)
+}
+
+// workerCount caps the requested number of workers by the open file limit.
+func workerCount(requested int) int {
+	if requested < 1 {
+		requested = 1
+	}
+	if limit := maxWorkers(); limit > 0 && requested > limit {
+		logger.Warnf("%d workers exceed the open file limit, using %d", requested, limit)
+		return limit
+	}
+	return requested
+}
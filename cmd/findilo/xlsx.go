@@ -0,0 +1,301 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	hpilo "github.com/hdhog/findilo/pkg/ilo"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// The workbook is a minimal SpreadsheetML package.
+const (
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	// Cell style 1 is the bold header, differential style 0 the amber fill
+	// of outdated firmware.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+<dxfs count="1"><dxf><fill><patternFill patternType="solid"><bgColor rgb="FFFFC000"/></patternFill></fill></dxf></dxfs>
+</styleSheet>`
+)
+
+const (
+	xlsxMaxWidth     = 60
+	xlsxMaxSheetName = 31
+)
+
+func init() {
+	kingpin.Flag("xlsx", "Same as --export-xlsx.").Hidden().PlaceHolder("FILE").StringVar(exportXLSX)
+}
+
+// xlsxTable is the content of one sheet.
+type xlsxTable struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+// xlsxTables returns a sheet with all hosts followed by a sheet per iLO
+// generation and a sheet per model.
+func xlsxTables(ilo []ILOInfo) []xlsxTable {
+	header, rows := tableRows(ilo)
+	tables := []xlsxTable{{name: "All", header: header, rows: rows}}
+	byGen := map[string][][]string{}
+	byModel := map[string][][]string{}
+	// tableRows sorted ilo, so rows[i] belongs to ilo[i].
+	for i, info := range ilo {
+		gen := summaryUnknown
+		if g := hpilo.Generation(info.HW); g != 0 {
+			gen = fmt.Sprintf("iLO %d", g)
+		}
+		byGen[gen] = append(byGen[gen], rows[i])
+		byModel[info.Model] = append(byModel[info.Model], rows[i])
+	}
+	gens := []string{}
+	for gen := range byGen {
+		gens = append(gens, gen)
+	}
+	sort.Slice(gens, func(i, j int) bool {
+		gi, gj := hpilo.Generation(gens[i]), hpilo.Generation(gens[j])
+		if gi == 0 || gj == 0 {
+			return gj == 0 && gi != 0
+		}
+		return gi < gj
+	})
+	for _, gen := range gens {
+		tables = append(tables, xlsxTable{name: gen, header: header, rows: byGen[gen]})
+	}
+	models := []string{}
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		tables = append(tables, xlsxTable{name: model, header: header, rows: byModel[model]})
+	}
+	return tables
+}
+
+// xlsxSheetNames makes the sheet names valid for Excel: at most 31
+// characters, none of []:*?/\ and unique regardless of case.
+func xlsxSheetNames(tables []xlsxTable) []string {
+	invalid := strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "-", "/", "-", `\`, "-")
+	seen := map[string]bool{}
+	names := []string{}
+	for _, t := range tables {
+		base := strings.TrimSpace(invalid.Replace(t.name))
+		if base == "" {
+			base = notAvailable
+		}
+		name := base
+		for n := 2; ; n++ {
+			if r := []rune(name); len(r) > xlsxMaxSheetName {
+				name = string(r[:xlsxMaxSheetName])
+			}
+			if !seen[strings.ToLower(name)] {
+				break
+			}
+			suffix := fmt.Sprintf(" (%d)", n)
+			r := []rune(base)
+			if len(r) > xlsxMaxSheetName-len(suffix) {
+				r = r[:xlsxMaxSheetName-len(suffix)]
+			}
+			name = string(r) + suffix
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// writeXLSX writes the tables to an Excel workbook at path, one sheet
+// each. Header rows are bold, frozen and have an auto-filter, and FW cells
+// matching one of the outdated versions are highlighted in amber.
+func writeXLSX(path string, tables []xlsxTable, outdated []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	names := xlsxSheetNames(tables)
+	parts := []struct {
+		name, body string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(tables))},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook(names, tables)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(tables))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, t := range tables {
+		parts = append(parts, struct{ name, body string }{
+			fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxSheet(t.header, t.rows, outdated),
+		})
+	}
+	z := zip.NewWriter(f)
+	for _, p := range parts {
+		w, err := z.Create(p.name)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := w.Write([]byte(p.body)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := z.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func xlsxContentTypes(sheets int) string {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", i)
+	}
+	b.WriteString("</Types>")
+	return b.String()
+}
+
+// xlsxWorkbookRels links sheet i to rId i, the styles follow the sheets.
+func xlsxWorkbookRels(sheets int) string {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+"\n", sheets+1)
+	b.WriteString("</Relationships>")
+	return b.String()
+}
+
+// xlsxRange is the reference of a table, used by the auto-filter.
+func xlsxRange(header []string, rows [][]string) string {
+	return fmt.Sprintf("A1:%s%d", xlsxColumn(len(header)-1), len(rows)+1)
+}
+
+func xlsxWorkbook(names []string, tables []xlsxTable) string {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>`)
+	for i, name := range names {
+		b.WriteString(`<sheet name="`)
+		xml.EscapeText(&b, []byte(name))
+		fmt.Fprintf(&b, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	b.WriteString("</sheets>\n<definedNames>")
+	for i, t := range tables {
+		ref := fmt.Sprintf("'%s'!$A$1:$%s$%d", strings.Replace(names[i], "'", "''", -1), xlsxColumn(len(t.header)-1), len(t.rows)+1)
+		fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">`, i)
+		xml.EscapeText(&b, []byte(ref))
+		b.WriteString("</definedName>")
+	}
+	b.WriteString("</definedNames>\n</workbook>")
+	return b.String()
+}
+
+func xlsxSheet(header []string, rows [][]string, outdated []string) string {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+<cols>`)
+	for i, width := range xlsxWidths(header, rows) {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+	}
+	b.WriteString("</cols>\n<sheetData>")
+	xlsxRow(&b, 1, header, 1)
+	for i, row := range rows {
+		xlsxRow(&b, i+2, row, 0)
+	}
+	b.WriteString("</sheetData>\n")
+	fmt.Fprintf(&b, `<autoFilter ref="%s"/>`, xlsxRange(header, rows))
+	fw := -1
+	for i, h := range header {
+		if h == "FW" {
+			fw = i
+		}
+	}
+	if fw >= 0 && len(outdated) > 0 && len(rows) > 0 {
+		col := xlsxColumn(fw)
+		conds := []string{}
+		for _, v := range outdated {
+			conds = append(conds, fmt.Sprintf(`%s2="%s"`, col, strings.Replace(v, `"`, `""`, -1)))
+		}
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="%s2:%s%d"><cfRule type="expression" dxfId="0" priority="1"><formula>`, col, col, len(rows)+1)
+		xml.EscapeText(&b, []byte("OR("+strings.Join(conds, ",")+")"))
+		b.WriteString(`</formula></cfRule></conditionalFormatting>`)
+	}
+	b.WriteString("\n</worksheet>")
+	return b.String()
+}
+
+// xlsxRow writes the cells of a row as inline strings with the cell style.
+func xlsxRow(b *bytes.Buffer, n int, cells []string, style int) {
+	fmt.Fprintf(b, `<row r="%d">`, n)
+	for i, cell := range cells {
+		fmt.Fprintf(b, `<c r="%s%d" t="inlineStr"`, xlsxColumn(i), n)
+		if style != 0 {
+			fmt.Fprintf(b, ` s="%d"`, style)
+		}
+		b.WriteString(`><is><t xml:space="preserve">`)
+		xml.EscapeText(b, []byte(cell))
+		b.WriteString("</t></is></c>")
+	}
+	b.WriteString("</row>")
+}
+
+// xlsxWidths sizes every column to its longest cell. Excel does not
+// auto-size columns when opening a file.
+func xlsxWidths(header []string, rows [][]string) []int {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell) + 2; i < len(widths) && n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for i := range widths {
+		if widths[i] > xlsxMaxWidth {
+			widths[i] = xlsxMaxWidth
+		}
+	}
+	return widths
+}
+
+// xlsxColumn returns the letters of the zero based column i.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xlsxWorksheet is the part of a worksheet checked by the tests.
+type xlsxWorksheet struct {
+	Pane struct {
+		YSplit      int    `xml:"ySplit,attr"`
+		TopLeftCell string `xml:"topLeftCell,attr"`
+		State       string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Cols []struct {
+		Min   int `xml:"min,attr"`
+		Width int `xml:"width,attr"`
+	} `xml:"cols>col"`
+	Rows []struct {
+		Cells []struct {
+			Ref   string `xml:"r,attr"`
+			Style int    `xml:"s,attr"`
+			Text  string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	AutoFilter struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"autoFilter"`
+	Conditional []struct {
+		Sqref string `xml:"sqref,attr"`
+		Rule  struct {
+			Type    string `xml:"type,attr"`
+			DxfID   int    `xml:"dxfId,attr"`
+			Formula string `xml:"formula"`
+		} `xml:"cfRule"`
+	} `xml:"conditionalFormatting"`
+}
+
+func readXLSXSheet(t *testing.T, path, name string) xlsxWorksheet {
+	t.Helper()
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	for _, f := range z.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		raw, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet := xlsxWorksheet{}
+		if err := xml.Unmarshal(raw, &sheet); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return sheet
+	}
+	t.Fatalf("%s is not in the workbook", name)
+	return xlsxWorksheet{}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.xlsx")
+	long := strings.Repeat("x", 80)
+	tables := []xlsxTable{{
+		name:   "All",
+		header: []string{"IP", "Model", "FW"},
+		rows: [][]string{
+			{"10.0.0.1", "ProLiant DL380 Gen9", "2.50"},
+			{"10.0.0.2", long, `2.55 "beta"`},
+		},
+	}}
+	if err := writeXLSX(path, tables, []string{"2.50", `2.55 "beta"`}); err != nil {
+		t.Fatal(err)
+	}
+	sheet := readXLSXSheet(t, path, "xl/worksheets/sheet1.xml")
+
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("sheet has %d rows, want 3", len(sheet.Rows))
+	}
+	header := []string{}
+	for _, c := range sheet.Rows[0].Cells {
+		if c.Style != 1 {
+			t.Errorf("header cell %s has style %d, want the bold style 1", c.Ref, c.Style)
+		}
+		header = append(header, c.Text)
+	}
+	if !reflect.DeepEqual(header, tables[0].header) {
+		t.Errorf("header = %v, want %v", header, tables[0].header)
+	}
+	if got := sheet.Rows[2].Cells[2].Text; got != `2.55 "beta"` {
+		t.Errorf("C3 = %q", got)
+	}
+
+	if sheet.AutoFilter.Ref != "A1:C3" {
+		t.Errorf("autoFilter ref = %q, want A1:C3", sheet.AutoFilter.Ref)
+	}
+	if p := sheet.Pane; p.YSplit != 1 || p.TopLeftCell != "A2" || p.State != "frozen" {
+		t.Errorf("pane = %+v, want the first row frozen", p)
+	}
+
+	widths := []int{}
+	for _, c := range sheet.Cols {
+		widths = append(widths, c.Width)
+	}
+	if want := []int{len("10.0.0.1") + 2, xlsxMaxWidth, len(`2.55 "beta"`) + 2}; !reflect.DeepEqual(widths, want) {
+		t.Errorf("column widths = %v, want %v", widths, want)
+	}
+
+	if len(sheet.Conditional) != 1 {
+		t.Fatalf("%d conditional formats, want 1", len(sheet.Conditional))
+	}
+	cf := sheet.Conditional[0]
+	if cf.Sqref != "C2:C3" || cf.Rule.Type != "expression" || cf.Rule.DxfID != 0 {
+		t.Errorf("conditional format = %+v, want an expression on C2:C3 with the amber style 0", cf)
+	}
+	if want := `OR(C2="2.50",C2="2.55 ""beta""")`; cf.Rule.Formula != want {
+		t.Errorf("formula = %s, want %s", cf.Rule.Formula, want)
+	}
+}
+
+func TestWriteXLSXNoOutdated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.xlsx")
+	tables := []xlsxTable{{name: "All", header: []string{"IP", "FW"}, rows: [][]string{{"10.0.0.1", "2.50"}}}}
+	if err := writeXLSX(path, tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	if sheet := readXLSXSheet(t, path, "xl/worksheets/sheet1.xml"); len(sheet.Conditional) != 0 {
+		t.Errorf("conditional formats without --outdated-fw: %+v", sheet.Conditional)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeYAML writes the --output json document as YAML. It goes through
+// JSON so the keys and their order are the same in both formats.
+func writeYAML(w io.Writer, report ScanReport) error {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(raw, doc); err != nil {
+		return err
+	}
+	clearStyle(doc)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// clearStyle drops the JSON flow style and quoting kept by the decoder,
+// so the document is written in block style.
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
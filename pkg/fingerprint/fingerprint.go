@@ -0,0 +1,162 @@
+// Package fingerprint identifies the device behind the open ports of a
+// host. Fingerprinters are registered with an order; the ports of all of
+// them are probed in turn, and the first one that identifies the host
+// enriches the device it found.
+package fingerprint
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// NoTCPProbe is the port of a fingerprinter that probes in Identify, for
+// devices found without a TCP port, such as by a UDP ping.
+const NoTCPProbe = 0
+
+// Device is a device identified on a host.
+type Device struct {
+	IP         string
+	Type       string
+	HW         string
+	FW         string
+	Model      string
+	Serial     string
+	ServerName string
+	// Name is the name of the device itself, like the iLO name of an iLO.
+	Name string
+	// Details is the own data of the fingerprinter about the device.
+	Details interface{}
+}
+
+// Fingerprinter identifies one kind of device.
+type Fingerprinter interface {
+	// Ports returns the TCP ports that can lead to the device, in the
+	// order they are probed. It returns none when the device is not
+	// looked for, and NoTCPProbe to get Identify called without a probe.
+	Ports() []int
+	// Identify reads the device behind the open port of host. Without a
+	// device the next ports are tried, unless final is set: the host is
+	// this kind of device but could not be read. err is what failed, with
+	// or without a device.
+	Identify(ctx context.Context, host string, port int) (dev *Device, final bool, err error)
+	// Enrich collects more details about a device this fingerprinter
+	// identified. Several failures can be returned with errors.Join; the
+	// device is kept either way.
+	Enrich(ctx context.Context, dev *Device) error
+}
+
+// Step is a port of a registered fingerprinter.
+type Step struct {
+	Name  string
+	Port  int
+	order int
+	f     Fingerprinter
+}
+
+// Registry is a set of fingerprinters. The zero Registry is empty.
+type Registry struct {
+	steps []Step
+}
+
+// Default is the registry of Register.
+var Default = &Registry{}
+
+// Register adds f to the Default registry.
+func Register(order int, name string, f Fingerprinter) {
+	Default.Register(order, name, f)
+}
+
+// Register adds f to r. Fingerprinters are tried by increasing order;
+// name tells which one identified a host.
+func (r *Registry) Register(order int, name string, f Fingerprinter) {
+	r.steps = append(r.steps, Step{Name: name, order: order, f: f})
+	sort.SliceStable(r.steps, func(i, j int) bool {
+		return r.steps[i].order < r.steps[j].order
+	})
+}
+
+// Host tries the ports of every fingerprinter of a registry on one host.
+// It remembers the probed ports, so a port shared by several
+// fingerprinters is probed once. It is used by one goroutine at a time.
+type Host struct {
+	host  string
+	probe func(ctx context.Context, port int) bool
+	steps []Step
+	open  map[int]bool
+	first int
+}
+
+// NewHost returns the scan of host. probe reports whether a TCP port of
+// host is open.
+func (r *Registry) NewHost(host string, probe func(ctx context.Context, port int) bool) *Host {
+	h := &Host{host: host, probe: probe, open: map[int]bool{}, first: -1}
+	for _, s := range r.steps {
+		for _, port := range s.f.Ports() {
+			s.Port = port
+			h.steps = append(h.steps, s)
+		}
+	}
+	return h
+}
+
+func (h *Host) isOpen(ctx context.Context, port int) bool {
+	if port == NoTCPProbe {
+		return true
+	}
+	open, ok := h.open[port]
+	if !ok {
+		open = h.probe(ctx, port)
+		h.open[port] = open
+	}
+	return open
+}
+
+// Probe reports whether the host answers on a port of a fingerprinter.
+// Only the ports up to the first open one are probed.
+func (h *Host) Probe(ctx context.Context) bool {
+	for i, s := range h.steps {
+		if ctx.Err() != nil {
+			return false
+		}
+		if h.isOpen(ctx, s.Port) {
+			h.first = i
+			return true
+		}
+	}
+	return false
+}
+
+// Identify tries the fingerprinters from the open port found by Probe
+// until one identifies the host or gives a final answer, and enriches the
+// device it found. It returns the step that found the device and the
+// failures on the way, joined.
+func (h *Host) Identify(ctx context.Context) (*Device, Step, error) {
+	var errs []error
+	if h.first < 0 {
+		return nil, Step{}, nil
+	}
+	for _, s := range h.steps[h.first:] {
+		if ctx.Err() != nil {
+			break
+		}
+		if !h.isOpen(ctx, s.Port) {
+			continue
+		}
+		dev, final, err := s.f.Identify(ctx, h.host, s.Port)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if dev == nil && final {
+			break
+		}
+		if dev == nil {
+			continue
+		}
+		if err := s.f.Enrich(ctx, dev); err != nil {
+			errs = append(errs, err)
+		}
+		return dev, s, errors.Join(errs...)
+	}
+	return nil, Step{}, errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package fingerprint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fake identifies the hosts of found on its ports and fails with err.
+type fake struct {
+	name  string
+	ports []int
+	found map[string]bool
+	final bool
+	err   error
+	calls *[]string
+}
+
+func (f fake) Ports() []int { return f.ports }
+
+func (f fake) Identify(ctx context.Context, host string, port int) (*Device, bool, error) {
+	*f.calls = append(*f.calls, f.name)
+	if !f.found[host] {
+		return nil, f.final, f.err
+	}
+	return &Device{IP: host, Type: f.name}, true, f.err
+}
+
+func (f fake) Enrich(ctx context.Context, dev *Device) error {
+	*f.calls = append(*f.calls, f.name+" enrich")
+	return nil
+}
+
+func TestHostOrderAndProbes(t *testing.T) {
+	var calls []string
+	r := &Registry{}
+	r.Register(20, "second", fake{name: "second", ports: []int{443}, found: map[string]bool{"h": true}, calls: &calls})
+	r.Register(10, "first", fake{name: "first", ports: []int{17988, 443}, calls: &calls})
+	probed := map[int]int{}
+	h := r.NewHost("h", func(ctx context.Context, port int) bool {
+		probed[port]++
+		return port == 443
+	})
+	if !h.Probe(context.Background()) {
+		t.Fatal("Probe found no open port")
+	}
+	dev, step, err := h.Identify(context.Background())
+	if dev == nil || dev.Type != "second" || err != nil {
+		t.Fatalf("Identify = %+v, %v", dev, err)
+	}
+	if step.Name != "second" || step.Port != 443 {
+		t.Errorf("step = %+v, want second on 443", step)
+	}
+	if want := []string{"first", "second", "second enrich"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if want := map[int]int{17988: 1, 443: 1}; !reflect.DeepEqual(probed, want) {
+		t.Errorf("probes = %v, want %v", probed, want)
+	}
+}
+
+func TestHostFinal(t *testing.T) {
+	var calls []string
+	failed := errors.New("no XML")
+	r := &Registry{}
+	r.Register(10, "first", fake{name: "first", ports: []int{NoTCPProbe}, final: true, err: failed, calls: &calls})
+	r.Register(20, "second", fake{name: "second", ports: []int{NoTCPProbe}, found: map[string]bool{"h": true}, calls: &calls})
+	h := r.NewHost("h", func(ctx context.Context, port int) bool {
+		t.Errorf("port %d was probed", port)
+		return false
+	})
+	if !h.Probe(context.Background()) {
+		t.Fatal("Probe is false without a TCP port")
+	}
+	dev, _, err := h.Identify(context.Background())
+	if dev != nil {
+		t.Errorf("Identify after a final answer = %+v", dev)
+	}
+	if !errors.Is(err, failed) {
+		t.Errorf("err = %v, want %v", err, failed)
+	}
+	if want := []string{"first"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestHostClosed(t *testing.T) {
+	var calls []string
+	r := &Registry{}
+	r.Register(10, "first", fake{name: "first", ports: []int{17988}, calls: &calls})
+	h := r.NewHost("h", func(ctx context.Context, port int) bool { return false })
+	if h.Probe(context.Background()) {
+		t.Error("Probe is true without an open port")
+	}
+	if dev, _, _ := h.Identify(context.Background()); dev != nil || len(calls) != 0 {
+		t.Errorf("Identify of a closed host = %+v, calls %v", dev, calls)
+	}
+}
@@ -0,0 +1,208 @@
+package ilo
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Doer sends HTTP requests. An *http.Client is a Doer; iLO certificates
+// are self-signed, so its TLS config should not verify them. Clients that
+// do not follow redirects let FetchXML see the HTTPS redirect of newer
+// iLO 5 firmware, others follow it themselves.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Info is what Discover reads about an iLO.
+type Info struct {
+	IP         string `json:"ip"`
+	HW         string `json:"hw"`
+	FW         string `json:"fw"`
+	Model      string `json:"model"`
+	Serial     string `json:"serial"`
+	ServerName string `json:"server_name"`
+	ILOName    string `json:"ilo_name"`
+	// Checksum is the SHA-1 of the RIMP document, in hex.
+	Checksum string `json:"xml_sha1"`
+	// ResponseTime is how long the RIMP request took.
+	ResponseTime time.Duration `json:"-"`
+}
+
+// Steps of Discover, in Error.
+const (
+	StepXML   = "xml"
+	StepParse = "parse"
+	StepNames = "names"
+)
+
+// Error is a failed step of Discover.
+type Error struct {
+	Step string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Step + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Discover reads the RIMP document of the iLO at host and its server and
+// iLO names. A zero port uses the default HTTP and HTTPS ports, any other
+// port is requested over HTTPS. When the document still has the Checksum
+// of known, a copy of known is returned without parsing the document or
+// requesting the names. A failed step is an *Error; when only the names
+// could not be read, the Info is returned with it.
+func Discover(ctx context.Context, c Doer, host string, port int, known *Info) (*Info, error) {
+	start := time.Now()
+	body, err := FetchXML(ctx, c, host, port)
+	if err != nil {
+		return nil, &Error{Step: StepXML, Err: err}
+	}
+	elapsed := time.Since(start)
+	sum := sha1.Sum(body)
+	checksum := hex.EncodeToString(sum[:])
+	if known != nil && known.Checksum == checksum {
+		info := *known
+		info.ResponseTime = elapsed
+		return &info, nil
+	}
+	r, err := ParseRIMP(body)
+	if err != nil {
+		return nil, &Error{Step: StepParse, Err: err}
+	}
+	info := &Info{
+		IP:           host,
+		HW:           r.HW(),
+		FW:           r.FW(),
+		Model:        r.Model(),
+		Serial:       r.Serial(),
+		Checksum:     checksum,
+		ResponseTime: elapsed,
+	}
+	info.ServerName, info.ILOName, err = FetchNames(ctx, c, host, port, Generation(info.HW))
+	if err != nil {
+		return info, &Error{Step: StepNames, Err: err}
+	}
+	return info, nil
+}
+
+// URL builds the URL of an iLO page. A zero port is the default port of
+// scheme.
+func URL(scheme, host string, port int, path string) string {
+	hostport := host
+	if strings.Contains(host, ":") {
+		hostport = "[" + host + "]"
+	}
+	if port != 0 {
+		hostport = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, hostport, path)
+}
+
+const xmlPath = "/xmldata?item=all"
+
+// FetchXML fetches the RIMP document. When the iLO redirects HTTP to
+// HTTPS the request is sent again over HTTPS. A non-zero port is
+// requested over HTTPS only.
+func FetchXML(ctx context.Context, c Doer, host string, port int) ([]byte, error) {
+	if port != 0 {
+		raw, _, err := get(ctx, c, URL("https", host, port, xmlPath), nil)
+		return raw, err
+	}
+	raw, status, err := get(ctx, c, URL("http", host, 0, xmlPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 && status < 400 {
+		raw, _, err = get(ctx, c, URL("https", host, 0, xmlPath), nil)
+	}
+	return raw, err
+}
+
+// FetchNames returns the server and iLO names, from the login page of
+// iLO 2 and older and from /json/login_session of newer generations.
+func FetchNames(ctx context.Context, c Doer, host string, port, generation int) (string, string, error) {
+	if generation >= 3 {
+		return FetchSessionNames(ctx, c, host, port)
+	}
+	return FetchPageNames(ctx, c, host, port)
+}
+
+// sessionNames is the part of /json/login_session with the names.
+type sessionNames struct {
+	Name string `json:"server_name"`
+	Cn   string `json:"cn"`
+}
+
+// FetchSessionNames returns the server and iLO names of /json/login_session.
+func FetchSessionNames(ctx context.Context, c Doer, host string, port int) (string, string, error) {
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	raw, _, err := get(ctx, c, URL("https", host, port, "/json/login_session?null"), header)
+	if err != nil {
+		return "", "", err
+	}
+	names := &sessionNames{}
+	if err := json.Unmarshal(raw, names); err != nil {
+		return "", "", err
+	}
+	return names.Name, names.Cn, nil
+}
+
+var (
+	serverNameRe = regexp.MustCompile(`serverName\="([\w-]+)"`)
+	nicNameRe    = regexp.MustCompile(`nicName\="([\w-]+)"`)
+)
+
+// FetchPageNames returns the server and iLO names of the login page.
+func FetchPageNames(ctx context.Context, c Doer, host string, port int) (string, string, error) {
+	url := URL("http", host, 0, "/")
+	if port != 0 {
+		url = URL("https", host, port, "/")
+	}
+	raw, _, err := get(ctx, c, url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	serverName, iloName := "", ""
+	if match := serverNameRe.FindSubmatch(raw); match != nil {
+		serverName = string(match[1])
+	}
+	if match := nicNameRe.FindSubmatch(raw); match != nil {
+		iloName = string(match[1])
+	}
+	return serverName, iloName, nil
+}
+
+// get returns the body and the status code of a GET of url.
+func get(ctx context.Context, c Doer, url string, header http.Header) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return raw, resp.StatusCode, nil
+}
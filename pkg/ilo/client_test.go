@@ -0,0 +1,358 @@
+package ilo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tlsILO serves the RIMP document and /json/login_session over HTTPS and
+// returns its port. names counts the name requests.
+func tlsILO(t *testing.T, rimp []byte, names *int32) int {
+	session := fixture(t, "login_session.json")
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xmldata":
+			w.Write(rimp)
+		case "/json/login_session":
+			atomic.AddInt32(names, 1)
+			w.Write(session)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return serverPort(t, srv)
+}
+
+func serverPort(t *testing.T, srv *httptest.Server) int {
+	_, portStr, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return port
+}
+
+func insecureClient() *http.Client {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	return srv.Client()
+}
+
+func TestDiscover(t *testing.T) {
+	var names int32
+	port := tlsILO(t, fixture(t, "ilo4.xml"), &names)
+	c := insecureClient()
+	info, err := Discover(context.Background(), c, "127.0.0.1", port, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{IP: "127.0.0.1", HW: "iLO 4", FW: "2.55", Model: "ProLiant DL380 Gen9", Serial: "CZ1234ABCD", ServerName: "srv-gen10", ILOName: "ilo-srv-gen10"}
+	got := *info
+	got.Checksum, got.ResponseTime = "", 0
+	if got != want {
+		t.Errorf("Discover = %+v, want %+v", got, want)
+	}
+	if len(info.Checksum) != 40 {
+		t.Errorf("Checksum = %q, want a SHA-1 in hex", info.Checksum)
+	}
+
+	again, err := Discover(context.Background(), c, "127.0.0.1", port, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == info || again.ServerName != "srv-gen10" {
+		t.Errorf("unchanged Discover = %p %+v, want a copy of %p", again, again, info)
+	}
+	if n := atomic.LoadInt32(&names); n != 1 {
+		t.Errorf("names were requested %d times, want 1", n)
+	}
+
+	changed := *info
+	changed.Checksum = "0000"
+	if _, err := Discover(context.Background(), c, "127.0.0.1", port, &changed); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&names); n != 2 {
+		t.Errorf("names were requested %d times after a change, want 2", n)
+	}
+}
+
+func TestDiscoverSteps(t *testing.T) {
+	c := insecureClient()
+	rimp := fixture(t, "ilo4.xml")
+	broken := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xmldata" {
+			w.Write([]byte("<RIMP><HSI>"))
+			return
+		}
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	noNames := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/xmldata" {
+			w.Write(rimp)
+			return
+		}
+		w.Write([]byte("not json"))
+	}))
+	defer noNames.Close()
+	closed := httptest.NewTLSServer(http.NotFoundHandler())
+	closedPort := serverPort(t, closed)
+	closed.Close()
+
+	for _, tt := range []struct {
+		name     string
+		port     int
+		step     string
+		withInfo bool
+	}{
+		{"closed", closedPort, StepXML, false},
+		{"broken XML", serverPort(t, broken), StepParse, false},
+		{"no names", serverPort(t, noNames), StepNames, true},
+	} {
+		info, err := Discover(context.Background(), c, "127.0.0.1", tt.port, nil)
+		var e *Error
+		if !errors.As(err, &e) || e.Step != tt.step {
+			t.Errorf("%s: err = %v, want a %s step error", tt.name, err, tt.step)
+		}
+		if (info != nil) != tt.withInfo {
+			t.Errorf("%s: info = %+v", tt.name, info)
+		}
+	}
+}
+
+// fixtureDoer sends the requests of any host to srv and records their
+// URLs, so that the URLs built for a host can be checked.
+type fixtureDoer struct {
+	srv  *httptest.Server
+	urls []string
+}
+
+func (d *fixtureDoer) Do(req *http.Request) (*http.Response, error) {
+	d.urls = append(d.urls, req.URL.String())
+	u, _ := url.Parse(d.srv.URL)
+	req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+	return d.srv.Client().Do(req)
+}
+
+// fixtureILO serves rimp on /xmldata, the iLO 2 login page on / and
+// login_session.json on /json/login_session.
+func fixtureILO(t *testing.T, rimp string) *fixtureDoer {
+	xml, page, session := fixture(t, rimp), fixture(t, "ilo2-login.html"), fixture(t, "login_session.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xmldata":
+			w.Write(xml)
+		case "/json/login_session":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(session)
+		case "/":
+			w.Write(page)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &fixtureDoer{srv: srv}
+}
+
+func TestFetchXML(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		port int
+		url  string
+		hw   string
+	}{
+		{"ilo2.xml", 0, "http://10.0.0.1/xmldata?item=all", "iLO 2"},
+		{"ilo3.xml", 0, "http://10.0.0.1/xmldata?item=all", "iLO 3"},
+		{"ilo4.xml", 8443, "https://10.0.0.1:8443/xmldata?item=all", "iLO 4"},
+		{"ilo5.xml", 0, "http://10.0.0.1/xmldata?item=all", "iLO 5"},
+		{"ilo5-empty.xml", 0, "http://10.0.0.1/xmldata?item=all", "iLO 5"},
+	} {
+		d := fixtureILO(t, tt.file)
+		raw, err := FetchXML(context.Background(), d, "10.0.0.1", tt.port)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if want := fixture(t, tt.file); !bytes.Equal(raw, want) {
+			t.Errorf("%s: FetchXML = %q, want the fixture", tt.file, raw)
+		}
+		if len(d.urls) != 1 || d.urls[0] != tt.url {
+			t.Errorf("%s: requested %q, want %q", tt.file, d.urls, tt.url)
+		}
+		r, err := ParseRIMP(raw)
+		if err != nil || r.HW() != tt.hw {
+			t.Errorf("%s: ParseRIMP = %+v, %v, want %s", tt.file, r, err, tt.hw)
+		}
+	}
+}
+
+func TestFetchNames(t *testing.T) {
+	for _, tt := range []struct {
+		file                string
+		port                int
+		url                 string
+		serverName, iloName string
+	}{
+		{"ilo2.xml", 0, "http://10.0.0.1/", "srv-g5", "ilo-srv-g5"},
+		{"ilo2.xml", 8443, "https://10.0.0.1:8443/", "srv-g5", "ilo-srv-g5"},
+		{"ilo3.xml", 0, "https://10.0.0.1/json/login_session?null", "srv-gen10", "ilo-srv-gen10"},
+		{"ilo4.xml", 0, "https://10.0.0.1/json/login_session?null", "srv-gen10", "ilo-srv-gen10"},
+		{"ilo5.xml", 8443, "https://10.0.0.1:8443/json/login_session?null", "srv-gen10", "ilo-srv-gen10"},
+		{"ilo5-empty.xml", 0, "https://10.0.0.1/json/login_session?null", "srv-gen10", "ilo-srv-gen10"},
+	} {
+		d := fixtureILO(t, tt.file)
+		r, err := ParseRIMP(fixture(t, tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverName, iloName, err := FetchNames(context.Background(), d, "10.0.0.1", tt.port, Generation(r.HW()))
+		if err != nil {
+			t.Errorf("%s on port %d: %v", tt.file, tt.port, err)
+			continue
+		}
+		if serverName != tt.serverName || iloName != tt.iloName {
+			t.Errorf("%s on port %d: FetchNames = %q, %q, want %q, %q", tt.file, tt.port, serverName, iloName, tt.serverName, tt.iloName)
+		}
+		if len(d.urls) != 1 || d.urls[0] != tt.url {
+			t.Errorf("%s on port %d: requested %q, want %q", tt.file, tt.port, d.urls, tt.url)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	for _, tt := range []struct {
+		host string
+		port int
+		want string
+	}{
+		{"10.0.0.1", 0, "https://10.0.0.1/x"},
+		{"10.0.0.1", 8443, "https://10.0.0.1:8443/x"},
+		{"fe80::1", 0, "https://[fe80::1]/x"},
+		{"fe80::1", 8443, "https://[fe80::1]:8443/x"},
+	} {
+		if got := URL("https", tt.host, tt.port, "/x"); got != tt.want {
+			t.Errorf("URL(%q, %d) = %q, want %q", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+// slowServer answers after delay, or writes the headers first and delays
+// the body with slowBody.
+func slowServer(t *testing.T, delay time.Duration, slowBody bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slowBody {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchXMLTimeout(t *testing.T) {
+	for _, slowBody := range []bool{false, true} {
+		d := &fixtureDoer{srv: slowServer(t, 5*time.Second, slowBody)}
+		c := &http.Client{Transport: doerTransport{d}, Timeout: 100 * time.Millisecond}
+		start := time.Now()
+		_, err := FetchXML(context.Background(), c, "10.0.0.1", 0)
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("slow body %v: err = %v, want a timeout", slowBody, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("slow body %v: FetchXML took %v with a 100ms timeout", slowBody, elapsed)
+		}
+	}
+}
+
+// doerTransport sends the requests of an http.Client through a Doer.
+type doerTransport struct{ d Doer }
+
+func (t doerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.d.Do(req)
+}
+
+// schemeDoer sends the HTTP requests of any host to plain and the HTTPS
+// ones to tls, records their URLs and does not follow redirects, like
+// the doer of the scan.
+type schemeDoer struct {
+	plain, tls *httptest.Server
+	urls       []string
+}
+
+func (d *schemeDoer) Do(req *http.Request) (*http.Response, error) {
+	d.urls = append(d.urls, req.URL.String())
+	srv := d.plain
+	if req.URL.Scheme == "https" {
+		srv = d.tls
+	}
+	u, _ := url.Parse(srv.URL)
+	req.URL.Host = u.Host
+	c := *srv.Client()
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return c.Do(req)
+}
+
+func TestFetchXMLRedirectToHTTPS(t *testing.T) {
+	rimp := fixture(t, "ilo5.xml")
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RequestURI() != xmlPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(rimp)
+	}))
+	defer tlsSrv.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}))
+	defer redirect.Close()
+	// An old iLO that answers over HTTP; its HTTPS must not be asked.
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(rimp)
+	}))
+	defer plain.Close()
+
+	for _, tt := range []struct {
+		name  string
+		plain *httptest.Server
+		port  int
+		urls  []string
+	}{
+		{"redirect", redirect, 0, []string{"http://10.0.0.1/xmldata?item=all", "https://10.0.0.1/xmldata?item=all"}},
+		{"plain HTTP", plain, 0, []string{"http://10.0.0.1/xmldata?item=all"}},
+		{"HTTPS port", redirect, 8443, []string{"https://10.0.0.1:8443/xmldata?item=all"}},
+	} {
+		d := &schemeDoer{plain: tt.plain, tls: tlsSrv}
+		raw, err := FetchXML(context.Background(), d, "10.0.0.1", tt.port)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(raw, rimp) {
+			t.Errorf("%s: FetchXML = %q, want the RIMP", tt.name, raw)
+		}
+		if !reflect.DeepEqual(d.urls, tt.urls) {
+			t.Errorf("%s: requested %q, want %q", tt.name, d.urls, tt.urls)
+		}
+	}
+}
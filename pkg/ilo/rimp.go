@@ -0,0 +1,77 @@
+// Package ilo reads what an HP iLO tells about itself without logging in:
+// the RIMP document on /xmldata and the server and iLO names.
+package ilo
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NotAvailable is the value of a field the iLO did not report.
+const NotAvailable = "N/A"
+
+// RIMP is the document of /xmldata?item=all.
+type RIMP struct {
+	XMLName xml.Name `xml:"RIMP"`
+	SBSN    string   `xml:"HSI>SBSN"`
+	SPN     string   `xml:"HSI>SPN"`
+	PN      string   `xml:"MP>PN"`
+	FWRI    string   `xml:"MP>FWRI"`
+	HWRI    string   `xml:"MP>HWRI"`
+}
+
+// ParseRIMP parses the /xmldata document.
+func ParseRIMP(body []byte) (*RIMP, error) {
+	r := &RIMP{}
+	if err := xml.Unmarshal(body, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+var iloRevision = regexp.MustCompile(`\((.*)\)`)
+
+// HW returns the iLO generation, such as "iLO 4".
+func (r *RIMP) HW() string {
+	match := iloRevision.FindStringSubmatch(r.PN)
+	if match == nil {
+		return NotAvailable
+	}
+	return strings.TrimSpace(match[1])
+}
+
+// Model returns the server model.
+func (r *RIMP) Model() string {
+	if len(r.SPN) == 0 {
+		return NotAvailable
+	}
+	return strings.TrimSpace(r.SPN)
+}
+
+// FW returns the iLO firmware version.
+func (r *RIMP) FW() string {
+	if len(r.FWRI) == 0 {
+		return NotAvailable
+	}
+	return strings.TrimSpace(r.FWRI)
+}
+
+// Serial returns the server serial number.
+func (r *RIMP) Serial() string {
+	return strings.TrimSpace(r.SBSN)
+}
+
+var generationRe = regexp.MustCompile(`^iLO (\d+)`)
+
+// Generation returns the iLO generation of a HW string such as "iLO 4",
+// or 0 when it is unknown.
+func Generation(hw string) int {
+	match := generationRe.FindStringSubmatch(hw)
+	if match == nil {
+		return 0
+	}
+	gen, _ := strconv.Atoi(match[1])
+	return gen
+}
@@ -0,0 +1,105 @@
+package ilo
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// fixture returns a file of testdata.
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParseRIMP(t *testing.T) {
+	for _, tt := range []struct {
+		file                  string
+		hw, fw, model, serial string
+	}{
+		{"ilo2.xml", "iLO 2", "2.33", "ProLiant DL380 G5", "GB8704ABCD"},
+		{"ilo3.xml", "iLO 3", "1.94", "ProLiant DL360 G7", "CZJ1230ABC"},
+		{"ilo4.xml", "iLO 4", "2.55", "ProLiant DL380 Gen9", "CZ1234ABCD"},
+		{"ilo5.xml", "iLO 5", "2.72", "ProLiant DL380 Gen10", "CZ2D1234AB"},
+		{"ilo5-empty.xml", "iLO 5", "2.72", NotAvailable, "CZ2D1234AB"},
+	} {
+		r, err := ParseRIMP(fixture(t, tt.file))
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if r.HW() != tt.hw || r.FW() != tt.fw || r.Model() != tt.model || r.Serial() != tt.serial {
+			t.Errorf("%s: HW %q FW %q Model %q Serial %q, want %q %q %q %q",
+				tt.file, r.HW(), r.FW(), r.Model(), r.Serial(), tt.hw, tt.fw, tt.model, tt.serial)
+		}
+	}
+}
+
+func TestParseRIMPEmptyElements(t *testing.T) {
+	r, err := ParseRIMP(fixture(t, "ilo5-empty.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SPN != "" || r.HWRI != "" {
+		t.Errorf("SPN %q HWRI %q, want empty", r.SPN, r.HWRI)
+	}
+	if r.Model() != NotAvailable {
+		t.Errorf("Model = %q, want %q", r.Model(), NotAvailable)
+	}
+}
+
+func TestParseRIMPBroken(t *testing.T) {
+	for _, body := range []string{"", "<RIMP><HSI>", "<html><body>Not an iLO</body></html>"} {
+		if _, err := ParseRIMP([]byte(body)); err == nil {
+			t.Errorf("ParseRIMP(%q) succeeded", body)
+		}
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	for _, tt := range []struct {
+		hw   string
+		want int
+	}{
+		{"iLO 2", 2},
+		{"iLO 3", 3},
+		{"iLO 4", 4},
+		{"iLO 5", 5},
+		{"iLO 6", 6},
+		{"N/A", 0},
+		{"", 0},
+		{"iLO", 0},
+		{"Integrated Lights-Out 4 (iLO 4)", 0},
+	} {
+		if got := Generation(tt.hw); got != tt.want {
+			t.Errorf("Generation(%q) = %d, want %d", tt.hw, got, tt.want)
+		}
+	}
+}
+
+// TestGenerationNames checks that iLO 2 reads its names from the login
+// page and the newer generations from /json/login_session.
+func TestGenerationNames(t *testing.T) {
+	for _, tt := range []struct {
+		generation int
+		url        string
+	}{
+		{0, "http://10.0.0.1/"},
+		{2, "http://10.0.0.1/"},
+		{3, "https://10.0.0.1/json/login_session?null"},
+		{5, "https://10.0.0.1/json/login_session?null"},
+	} {
+		d := fixtureILO(t, "ilo5.xml")
+		if _, _, err := FetchNames(context.Background(), d, "10.0.0.1", 0, tt.generation); err != nil {
+			t.Errorf("generation %d: %v", tt.generation, err)
+		}
+		if len(d.urls) != 1 || d.urls[0] != tt.url {
+			t.Errorf("generation %d: requested %q, want %q", tt.generation, d.urls, tt.url)
+		}
+	}
+}
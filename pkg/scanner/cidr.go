@@ -0,0 +1,150 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// cidrNode is a node of a binary radix tree over IPv4 address bits. A full
+// node covers its whole prefix.
+type cidrNode struct {
+	children [2]*cidrNode
+	full     bool
+}
+
+func (n *cidrNode) insert(addr uint32, bits int) {
+	node := n
+	for i := 0; i < bits; i++ {
+		if node.full {
+			return
+		}
+		b := (addr >> uint(31-i)) & 1
+		if node.children[b] == nil {
+			node.children[b] = &cidrNode{}
+		}
+		node = node.children[b]
+	}
+	node.full = true
+	node.children = [2]*cidrNode{}
+}
+
+// compact merges sibling prefixes that are both full into their parent.
+func (n *cidrNode) compact() {
+	for _, c := range n.children {
+		if c != nil {
+			c.compact()
+		}
+	}
+	if n.children[0] != nil && n.children[1] != nil && n.children[0].full && n.children[1].full {
+		n.full = true
+		n.children = [2]*cidrNode{}
+	}
+}
+
+func (n *cidrNode) collect(addr uint32, bits int, res []string) []string {
+	if n.full {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, addr)
+		return append(res, fmt.Sprintf("%s/%d", ip, bits))
+	}
+	for b, c := range n.children {
+		if c != nil {
+			res = c.collect(addr|uint32(b)<<uint(31-bits), bits+1, res)
+		}
+	}
+	return res
+}
+
+// AggregateCIDRs returns the minimal set of CIDRs covering the same IPv4
+// addresses as cidrs, and the input CIDRs it dropped as redundant or
+// merged. IPv6 networks are kept as they are.
+func AggregateCIDRs(cidrs []string) (merged, removed []string, err error) {
+	root := &cidrNode{}
+	var res, inputs []string
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(HostCIDR(cidr))
+		if err != nil {
+			return nil, nil, err
+		}
+		ip4 := ipnet.IP.To4()
+		if ip4 == nil {
+			res = append(res, ipnet.String())
+			continue
+		}
+		bits, _ := ipnet.Mask.Size()
+		root.insert(binary.BigEndian.Uint32(ip4), bits)
+		inputs = append(inputs, ipnet.String())
+	}
+	root.compact()
+	merged = root.collect(0, 0, nil)
+
+	kept := map[string]bool{}
+	for _, cidr := range merged {
+		kept[cidr] = true
+	}
+	for _, cidr := range inputs {
+		if !kept[cidr] {
+			removed = append(removed, cidr)
+		}
+	}
+	return append(merged, res...), removed, nil
+}
+
+// ExpandRanges replaces the IPv4 ranges in networks, 10.0.0.10-10.0.0.120
+// or 10.0.0.10-120, by the CIDRs that cover them exactly.
+func ExpandRanges(networks []string) ([]string, error) {
+	res := []string{}
+	for _, network := range networks {
+		i := strings.Index(network, "-")
+		if i < 0 || net.ParseIP(network[:i]) == nil {
+			res = append(res, network)
+			continue
+		}
+		cidrs, err := rangeCIDRs(network[:i], network[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", network, err)
+		}
+		res = append(res, cidrs...)
+	}
+	return res, nil
+}
+
+// rangeCIDRs returns the CIDRs of the addresses from first to last. A
+// last without dots replaces the last octet of first.
+func rangeCIDRs(first, last string) ([]string, error) {
+	from := net.ParseIP(first).To4()
+	if from == nil {
+		return nil, fmt.Errorf("%q is not an IPv4 address, ranges are IPv4 only", first)
+	}
+	if !strings.Contains(last, ".") {
+		last = first[:strings.LastIndex(first, ".")+1] + last
+	}
+	to := net.ParseIP(last).To4()
+	if to == nil {
+		return nil, fmt.Errorf("%q is not an IPv4 address", last)
+	}
+	start := uint64(binary.BigEndian.Uint32(from))
+	end := uint64(binary.BigEndian.Uint32(to))
+	if start > end {
+		return nil, fmt.Errorf("range ends before it starts")
+	}
+	res := []string{}
+	for start <= end {
+		// The largest block aligned at start that does not pass end.
+		bits := 32
+		for bits > 0 {
+			size := uint64(1) << uint(33-bits)
+			if start%size != 0 || start+size-1 > end {
+				break
+			}
+			bits--
+		}
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(start))
+		res = append(res, fmt.Sprintf("%s/%d", ip, bits))
+		start += uint64(1) << uint(32-bits)
+	}
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateCIDRs(t *testing.T) {
+	for _, tt := range []struct {
+		cidrs   []string
+		merged  []string
+		removed []string
+	}{
+		{[]string{"10.0.0.0/16", "10.0.5.0/24"}, []string{"10.0.0.0/16"}, []string{"10.0.5.0/24"}},
+		{[]string{"10.0.5.0/24", "10.0.0.0/16"}, []string{"10.0.0.0/16"}, []string{"10.0.5.0/24"}},
+		{[]string{"10.0.0.0/25", "10.0.0.128/25"}, []string{"10.0.0.0/24"}, []string{"10.0.0.0/25", "10.0.0.128/25"}},
+		{[]string{"10.0.0.0/25", "10.0.1.128/25"}, []string{"10.0.0.0/25", "10.0.1.128/25"}, nil},
+		{[]string{"10.0.0.1", "10.0.0.0/31"}, []string{"10.0.0.0/31"}, []string{"10.0.0.1/32"}},
+		{[]string{"fd00::/120", "10.0.0.0/24"}, []string{"10.0.0.0/24", "fd00::/120"}, nil},
+	} {
+		merged, removed, err := AggregateCIDRs(tt.cidrs)
+		if err != nil {
+			t.Errorf("%v: %v", tt.cidrs, err)
+			continue
+		}
+		if !reflect.DeepEqual(merged, tt.merged) {
+			t.Errorf("%v: merged = %v, want %v", tt.cidrs, merged, tt.merged)
+		}
+		if !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("%v: removed = %v, want %v", tt.cidrs, removed, tt.removed)
+		}
+	}
+	if _, _, err := AggregateCIDRs([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("10.0.0.0/33 was accepted")
+	}
+}
+
+func TestExpandRanges(t *testing.T) {
+	short := []string{
+		"10.0.0.10/31", "10.0.0.12/30", "10.0.0.16/28", "10.0.0.32/27",
+		"10.0.0.64/27", "10.0.0.96/28", "10.0.0.112/29", "10.0.0.120/32",
+	}
+	for _, tt := range []struct {
+		network string
+		want    []string
+	}{
+		{"10.0.0.10-120", short},
+		{"10.0.0.10-10.0.0.120", short},
+		{"10.0.0.0-10.0.1.255", []string{"10.0.0.0/23"}},
+		{"10.0.0.7-7", []string{"10.0.0.7/32"}},
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}},
+		{"ilo-web1.example.com", []string{"ilo-web1.example.com"}},
+	} {
+		got, err := ExpandRanges([]string{tt.network})
+		if err != nil {
+			t.Errorf("%s: %v", tt.network, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.network, got, tt.want)
+		}
+	}
+	for _, network := range []string{
+		"10.0.0.120-10",
+		"10.0.0.120-10.0.0.10",
+		"10.0.0.1-abc",
+		"10.0.0.1-10.0.0.300",
+		"fd00::1-fd00::2",
+	} {
+		if got, err := ExpandRanges([]string{network}); err == nil {
+			t.Errorf("%s was accepted as %v", network, got)
+		}
+	}
+}
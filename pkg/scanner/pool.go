@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+)
+
+// Feed sends the addresses of t on the returned channel, in order or, with
+// shuffle, in random order. The channel is closed after the last address
+// or when ctx is done.
+func Feed(ctx context.Context, t Targets, shuffle bool) <-chan string {
+	hosts := make(chan string)
+	go func() {
+		defer close(hosts)
+		each := t.Each
+		if shuffle {
+			each = t.EachShuffled
+		}
+		each(func(ip string) bool {
+			select {
+			case hosts <- ip:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return hosts
+}
+
+// Run calls scan for every address of t from the given number of workers
+// and returns when all are done. Once ctx is done no more addresses are
+// handed out; scan should return early as well.
+func Run(ctx context.Context, t Targets, workers int, shuffle bool, scan func(ctx context.Context, host string)) {
+	if n := t.Count(); workers > n {
+		workers = n
+	}
+	hosts := Feed(ctx, t, shuffle)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range hosts {
+				scan(ctx, host)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hostsOf returns n addresses of 10.0.0.0/24.
+func hostsOf(t *testing.T, n int) Targets {
+	t.Helper()
+	if n == 0 {
+		return Targets{}
+	}
+	tg, err := NewTargets([]string{"10.0.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips := []string{}
+	tg.Each(func(ip string) bool {
+		ips = append(ips, ip)
+		return len(ips) < n
+	})
+	return TargetsOf(ips)
+}
+
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		hosts, workers int
+		parallel       int
+	}{
+		{"empty", 0, 4, 0},
+		{"one worker", 5, 1, 1},
+		{"as many workers as hosts", 5, 5, 5},
+		{"more workers than hosts", 3, 10, 3},
+		{"not divisible", 10, 3, 3},
+	} {
+		var mu sync.Mutex
+		scanned := []string{}
+		active, most := 0, 0
+		// The scans wait until tt.parallel of them run at once, so a short
+		// count of workers shows up as a lower most.
+		full := make(chan struct{})
+		Run(context.Background(), hostsOf(t, tt.hosts), tt.workers, false, func(ctx context.Context, host string) {
+			mu.Lock()
+			scanned = append(scanned, host)
+			if active++; active > most {
+				most = active
+				if most == tt.parallel {
+					close(full)
+				}
+			}
+			mu.Unlock()
+			select {
+			case <-full:
+			case <-time.After(time.Second):
+			}
+			mu.Lock()
+			active--
+			mu.Unlock()
+		})
+		if len(scanned) != tt.hosts {
+			t.Errorf("%s: %d hosts scanned, want %d", tt.name, len(scanned), tt.hosts)
+		}
+		seen := map[string]bool{}
+		for _, host := range scanned {
+			if seen[host] {
+				t.Errorf("%s: %s was scanned twice", tt.name, host)
+			}
+			seen[host] = true
+		}
+		if most != tt.parallel {
+			t.Errorf("%s: %d hosts scanned at once, want %d", tt.name, most, tt.parallel)
+		}
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	Run(ctx, hostsOf(t, 100), 1, false, func(ctx context.Context, host string) {
+		if n++; n == 3 {
+			cancel()
+		}
+	})
+	// The feeder may already be waiting to hand out the next address.
+	if n > 4 {
+		t.Errorf("%d hosts scanned after the cancel at 3", n)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	tg := hostsOf(t, 20)
+	var ordered, shuffled []string
+	for ip := range Feed(context.Background(), tg, false) {
+		ordered = append(ordered, ip)
+	}
+	for ip := range Feed(context.Background(), tg, true) {
+		shuffled = append(shuffled, ip)
+	}
+	if len(ordered) != 20 || ordered[0] != "10.0.0.0" || ordered[19] != "10.0.0.19" {
+		t.Errorf("Feed = %v", ordered)
+	}
+	sort.Slice(shuffled, func(i, j int) bool {
+		a, b := IPKey(shuffled[i]), IPKey(shuffled[j])
+		return string(a[:]) < string(b[:])
+	})
+	if len(shuffled) != len(ordered) {
+		t.Fatalf("shuffled Feed gave %d addresses, want %d", len(shuffled), len(ordered))
+	}
+	for i := range ordered {
+		if shuffled[i] != ordered[i] {
+			t.Fatalf("shuffled Feed = %v, want the addresses of %v", shuffled, ordered)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// Probe connects to the TCP port of host with d and closes the connection.
+// A nil error means the port is open.
+func Probe(ctx context.Context, d *net.Dialer, host string, port int) error {
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,292 @@
+// Package scanner expands the networks to scan into addresses, probes
+// them and runs the workers of a scan. Package ilo reads the iLOs it
+// finds.
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+)
+
+// MinIPv6Prefix is the shortest IPv6 prefix that is expanded. A /112 has
+// 65536 addresses, as many as an IPv4 /16.
+const MinIPv6Prefix = 112
+
+// interval is an inclusive range of addresses, keyed like IPKey.
+type interval struct {
+	first, last [16]byte
+}
+
+// size returns the number of addresses of the interval. IPv6 networks are
+// at least /MinIPv6Prefix, so the count fits the low 64 bits.
+func (iv interval) size() uint64 {
+	return binary.BigEndian.Uint64(iv.last[8:]) - binary.BigEndian.Uint64(iv.first[8:]) + 1
+}
+
+// Targets is a set of addresses stored as sorted, disjoint intervals.
+// Addresses are generated as they are scanned, so a /8 does not take 16
+// million strings of memory. The zero Targets is empty.
+type Targets struct {
+	intervals []interval
+}
+
+// NewTargets returns the addresses of the networks, in CIDR notation or
+// single addresses. Overlapping networks
+// are merged, so every address is scanned once.
+func NewTargets(networks []string) (Targets, error) {
+	intervals := []interval{}
+	for _, ipNetwork := range networks {
+		ip, ipnet, err := net.ParseCIDR(HostCIDR(ipNetwork))
+		if err != nil {
+			return Targets{}, err
+		}
+		if ones, _ := ipnet.Mask.Size(); ip.To4() == nil && ones < MinIPv6Prefix {
+			return Targets{}, fmt.Errorf("%s: IPv6 networks must be /%d or longer, a shorter prefix has too many addresses to scan", ipNetwork, MinIPv6Prefix)
+		}
+		intervals = append(intervals, netInterval(ipnet))
+	}
+	return Targets{intervals: mergeIntervals(intervals)}, nil
+}
+
+// TargetsOf returns the set of a list of addresses.
+func TargetsOf(ips []string) Targets {
+	intervals := make([]interval, 0, len(ips))
+	for _, ip := range ips {
+		key := IPKey(ip)
+		intervals = append(intervals, interval{first: key, last: key})
+	}
+	return Targets{intervals: mergeIntervals(intervals)}
+}
+
+func netInterval(ipnet *net.IPNet) interval {
+	last := make(net.IP, len(ipnet.IP))
+	for i := range ipnet.IP {
+		last[i] = ipnet.IP[i] | ^ipnet.Mask[i]
+	}
+	var iv interval
+	copy(iv.first[:], ipnet.IP.To16())
+	copy(iv.last[:], last.To16())
+	return iv
+}
+
+// mergeIntervals sorts intervals and joins the ones that overlap or touch.
+func mergeIntervals(intervals []interval) []interval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return bytes.Compare(intervals[i].first[:], intervals[j].first[:]) < 0
+	})
+	merged := []interval{}
+	for _, iv := range intervals {
+		if n := len(merged); n > 0 {
+			cur := &merged[n-1]
+			next := cur.last
+			inc(next[:])
+			if bytes.Compare(iv.first[:], cur.last[:]) <= 0 || iv.first == next {
+				if bytes.Compare(iv.last[:], cur.last[:]) > 0 {
+					cur.last = iv.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// Count returns the number of addresses in the set.
+func (t Targets) Count() int {
+	n := uint64(0)
+	for _, iv := range t.intervals {
+		n += iv.size()
+	}
+	return int(n)
+}
+
+// Each calls f with every address in order until f returns false.
+func (t Targets) Each(f func(ip string) bool) {
+	for _, iv := range t.intervals {
+		for ip := iv.first; ; inc(ip[:]) {
+			if !f(net.IP(ip[:]).String()) {
+				return
+			}
+			if ip == iv.last {
+				break
+			}
+		}
+	}
+}
+
+// EachShuffled is Each in a random order. The order is a full-period
+// linear congruential sequence over the next power of two of the count,
+// skipping the indices past the end, so it needs no list of addresses.
+func (t Targets) EachShuffled(f func(ip string) bool) {
+	n := uint64(t.Count())
+	if n == 0 {
+		return
+	}
+	m := uint64(1)
+	for m < n {
+		m <<= 1
+	}
+	// With m a power of two, an odd c and a-1 divisible by 4, x -> a*x+c
+	// visits every value below m once.
+	a := rand.Uint64()&^3 | 1
+	c := rand.Uint64() | 1
+	x := rand.Uint64() & (m - 1)
+	for i := uint64(0); i < m; i++ {
+		x = (a*x + c) & (m - 1)
+		if x < n && !f(t.At(x)) {
+			return
+		}
+	}
+}
+
+// At returns the address with index i in the order of Each.
+func (t Targets) At(i uint64) string {
+	for _, iv := range t.intervals {
+		if size := iv.size(); i >= size {
+			i -= size
+			continue
+		}
+		ip := iv.first
+		hi := binary.BigEndian.Uint64(ip[:8])
+		lo := binary.BigEndian.Uint64(ip[8:])
+		if lo+i < lo {
+			hi++
+		}
+		binary.BigEndian.PutUint64(ip[:8], hi)
+		binary.BigEndian.PutUint64(ip[8:], lo+i)
+		return net.IP(ip[:]).String()
+	}
+	return ""
+}
+
+// Union returns the addresses that are in t or in o.
+func (t Targets) Union(o Targets) Targets {
+	intervals := append(append([]interval{}, t.intervals...), o.intervals...)
+	return Targets{intervals: mergeIntervals(intervals)}
+}
+
+// Contains reports whether ip is in the set.
+func (t Targets) Contains(ip string) bool {
+	key := IPKey(ip)
+	i := sort.Search(len(t.intervals), func(i int) bool {
+		return bytes.Compare(t.intervals[i].last[:], key[:]) >= 0
+	})
+	return i < len(t.intervals) && bytes.Compare(t.intervals[i].first[:], key[:]) <= 0
+}
+
+// Exclude returns the set without the addresses of the networks.
+func (t Targets) Exclude(networks []*net.IPNet) Targets {
+	intervals := make([]interval, 0, len(networks))
+	for _, ipnet := range networks {
+		intervals = append(intervals, netInterval(ipnet))
+	}
+	return t.Minus(Targets{intervals: mergeIntervals(intervals)})
+}
+
+// Minus returns the addresses of t that are not in o. Both sets are sorted,
+// so they are walked once side by side.
+func (t Targets) Minus(o Targets) Targets {
+	res := []interval{}
+	j := 0
+	for _, iv := range t.intervals {
+		for j < len(o.intervals) && bytes.Compare(o.intervals[j].last[:], iv.first[:]) < 0 {
+			j++
+		}
+		// The rest of iv is dropped with a flag rather than by moving
+		// iv.first past iv.last, which wraps to :: at the end of IPv6.
+		covered := false
+		for k := j; k < len(o.intervals) && bytes.Compare(o.intervals[k].first[:], iv.last[:]) <= 0; k++ {
+			ex := o.intervals[k]
+			if bytes.Compare(iv.first[:], ex.first[:]) < 0 {
+				before := interval{first: iv.first, last: ex.first}
+				dec(before.last[:])
+				res = append(res, before)
+			}
+			if bytes.Compare(ex.last[:], iv.last[:]) >= 0 {
+				covered = true
+				break
+			}
+			iv.first = ex.last
+			inc(iv.first[:])
+		}
+		if !covered {
+			res = append(res, iv)
+		}
+	}
+	return Targets{intervals: res}
+}
+
+// Ranges returns the intervals of the set as "first-last" strings, or a
+// single address for one address.
+func (t Targets) Ranges() []string {
+	res := make([]string, 0, len(t.intervals))
+	for _, iv := range t.intervals {
+		first := net.IP(iv.first[:]).String()
+		if iv.first == iv.last {
+			res = append(res, first)
+			continue
+		}
+		res = append(res, first+"-"+net.IP(iv.last[:]).String())
+	}
+	return res
+}
+
+// ParseRanges reads a set written by Ranges.
+func ParseRanges(ranges []string) (Targets, error) {
+	intervals := make([]interval, 0, len(ranges))
+	for _, r := range ranges {
+		first, last := r, r
+		if i := strings.LastIndex(r, "-"); i >= 0 {
+			first, last = r[:i], r[i+1:]
+		}
+		if net.ParseIP(first) == nil || net.ParseIP(last) == nil {
+			return Targets{}, fmt.Errorf("%q is not an address or a range of addresses", r)
+		}
+		intervals = append(intervals, interval{first: IPKey(first), last: IPKey(last)})
+	}
+	return Targets{intervals: mergeIntervals(intervals)}, nil
+}
+
+// IPKey returns a comparable key that orders addresses numerically, so
+// 10.0.0.2 comes before 10.0.0.100. IPv4 addresses are keyed in their
+// IPv4-mapped form to share the key with IPv6.
+func IPKey(ip string) [16]byte {
+	var key [16]byte
+	copy(key[:], net.ParseIP(ip).To16())
+	return key
+}
+
+// HostCIDR turns a single address into a host network.
+func HostCIDR(network string) string {
+	if strings.Contains(network, "/") {
+		return network
+	}
+	if strings.Contains(network, ":") {
+		return network + "/128"
+	}
+	return network + "/32"
+}
+
+func inc(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]++
+		if ip[j] > 0 {
+			break
+		}
+	}
+}
+
+func dec(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]--
+		if ip[j] != 0xff {
+			break
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestNewTargetsOverlap(t *testing.T) {
+	for _, tt := range []struct {
+		networks []string
+		count    int
+		ranges   []string
+	}{
+		{[]string{"10.0.0.0/24", "10.0.0.0/16"}, 65536, []string{"10.0.0.0-10.0.255.255"}},
+		{[]string{"10.0.0.0/16", "10.0.0.0/24"}, 65536, []string{"10.0.0.0-10.0.255.255"}},
+		{[]string{"10.0.0.0/25", "10.0.0.64/26", "10.0.0.5"}, 128, []string{"10.0.0.0-10.0.0.127"}},
+		{[]string{"10.0.0.0/25", "10.0.0.128/25"}, 256, []string{"10.0.0.0-10.0.0.255"}},
+		{[]string{"10.0.0.1", "10.0.0.1", "10.0.0.3"}, 2, []string{"10.0.0.1", "10.0.0.3"}},
+		{[]string{"192.168.1.0/30", "10.0.0.0/30"}, 8, []string{"10.0.0.0-10.0.0.3", "192.168.1.0-192.168.1.3"}},
+		{[]string{"fd00::/120", "fd00::10"}, 256, []string{"fd00::-fd00::ff"}},
+	} {
+		tg, err := NewTargets(tt.networks)
+		if err != nil {
+			t.Errorf("%v: %v", tt.networks, err)
+			continue
+		}
+		if n := tg.Count(); n != tt.count {
+			t.Errorf("%v: Count = %d, want %d", tt.networks, n, tt.count)
+		}
+		if r := tg.Ranges(); !reflect.DeepEqual(r, tt.ranges) {
+			t.Errorf("%v: Ranges = %v, want %v", tt.networks, r, tt.ranges)
+		}
+		seen := map[string]bool{}
+		tg.Each(func(ip string) bool {
+			if seen[ip] {
+				t.Errorf("%v: %s twice", tt.networks, ip)
+			}
+			seen[ip] = true
+			return true
+		})
+		if len(seen) != tt.count {
+			t.Errorf("%v: Each gave %d addresses, want %d", tt.networks, len(seen), tt.count)
+		}
+	}
+}
+
+func TestNewTargetsShortIPv6(t *testing.T) {
+	if _, err := NewTargets([]string{"fd00::/64"}); err == nil {
+		t.Error("a /64 was accepted")
+	}
+}
+
+func TestExcludeOverlap(t *testing.T) {
+	tg, err := NewTargets([]string{"10.0.0.0/24", "10.0.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var excluded []*net.IPNet
+	for _, network := range []string{"10.0.1.0/24", "10.0.1.0/25", "10.0.2.0/24"} {
+		_, ipnet, _ := net.ParseCIDR(network)
+		excluded = append(excluded, ipnet)
+	}
+	got := tg.Exclude(excluded)
+	if n := got.Count(); n != 65536-512 {
+		t.Errorf("Count = %d, want %d", n, 65536-512)
+	}
+	want := []string{"10.0.0.0-10.0.0.255", "10.0.3.0-10.0.255.255"}
+	if r := got.Ranges(); !reflect.DeepEqual(r, want) {
+		t.Errorf("Ranges = %v, want %v", r, want)
+	}
+	if got.Contains("10.0.1.200") || !got.Contains("10.0.3.0") {
+		t.Error("Contains does not match the excluded networks")
+	}
+}
+
+func TestExcludeEndOfIPv6(t *testing.T) {
+	tg, err := NewTargets([]string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00/120"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		network string
+		ranges  []string
+	}{
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fff0/124", []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffef"}},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00/120", []string{}},
+	} {
+		_, ipnet, _ := net.ParseCIDR(tt.network)
+		got := tg.Exclude([]*net.IPNet{ipnet})
+		if r := got.Ranges(); !reflect.DeepEqual(r, tt.ranges) {
+			t.Errorf("without %s: Ranges = %v, want %v", tt.network, r, tt.ranges)
+		}
+	}
+}